@@ -7,13 +7,15 @@ import (
 	"bgt_boost/internal/services"
 	"bgt_boost/internal/utils"
 	"context"
+	"flag"
 	"fmt"
 	"log"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
-	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/robfig/cron/v3"
 )
 
@@ -25,41 +27,86 @@ func main() {
 	}
 	defer db.Disconnect()
 
-	go func() {
-		api.SetupValidator()
-		server := api.NewServer(config, &db)
-		if err := server.ListenAndServe(); err != nil {
-			panic(fmt.Sprintf("cannot start server: %s", err))
-		}
-	}()
-
-	ethClient, err := ethclient.Dial(config.RPC_URL)
+	rpcPool, err := repository.DialRPCPool(config.RPC_URLS, uint64(config.RPCMaxLagBlocks), time.Duration(config.RPCCheckSeconds)*time.Second)
 	if err != nil {
 		panic(fmt.Sprintf("cannot connect to eth client: %s", err))
 	}
-	defer ethClient.Close()
-	ethRepository := repository.NewEthRepository(ethClient, config)
+	defer rpcPool.Close()
+	ethRepository := repository.NewEthRepository(rpcPool, config)
 
 	requestRepository := repository.NewRequestRepository([]int{})
 	signerService := services.NewSignerService(config.Web3SignerURL, &requestRepository)
 	boostService := services.NewBoostService(config, &db, &ethRepository, &signerService)
+	if len(os.Args) > 1 && os.Args[1] == "backfill" {
+		runBackfillCommand(os.Args[2:], boostService)
+		return
+	}
+	if !config.DryRun {
+		if err := boostService.ResumeTransactions(context.Background()); err != nil {
+			panic(fmt.Sprintf("cannot resume transactions: %s", err))
+		}
+	}
+	if err := boostService.LoadActivationSchedule(context.Background()); err != nil {
+		panic(fmt.Sprintf("cannot load activation schedule: %s", err))
+	}
 
-	c := cron.New(cron.WithSeconds())
-	_, err = c.AddFunc(config.CronSchedule, func() {
-		err = boostService.BoostValidator(context.Background())
-		if err != nil {
-			panic(fmt.Sprintf("cannot boost validator: %s", err))
+	go func() {
+		api.SetupValidator()
+		server := api.NewServer(config, &db, &boostService)
+		if err := server.ListenAndServe(); err != nil {
+			panic(fmt.Sprintf("cannot start server: %s", err))
 		}
+	}()
+
+	c := cron.New(cron.WithSeconds(), cron.WithChain(cron.Recover(cron.DefaultLogger), cron.SkipIfStillRunning(cron.DefaultLogger)))
+	_, err = c.AddFunc(config.CronSchedule, func() {
+		runBoost(config, boostService)
 		utils.PrintNextExecution(c)
 	})
-
-	err = boostService.BoostValidator(context.Background())
 	if err != nil {
-		panic(fmt.Sprintf("cannot boost validator: %s", err))
+		panic(fmt.Sprintf("cannot schedule boost job: %s", err))
 	}
+	if config.IndexerSchedule != "" {
+		_, err = c.AddFunc(config.IndexerSchedule, func() {
+			runBackfill(boostService)
+		})
+		if err != nil {
+			panic(fmt.Sprintf("cannot schedule indexer job: %s", err))
+		}
+	}
+	if config.ReconcileSchedule != "" {
+		_, err = c.AddFunc(config.ReconcileSchedule, func() {
+			runReconcile(config, boostService)
+		})
+		if err != nil {
+			panic(fmt.Sprintf("cannot schedule reconcile job: %s", err))
+		}
+	}
+	if config.ReorgCheckSchedule != "off" && !config.DryRun {
+		_, err = c.AddFunc(config.ReorgCheckSchedule, func() {
+			runVerifyTransactions(boostService)
+		})
+		if err != nil {
+			panic(fmt.Sprintf("cannot schedule reorg check job: %s", err))
+		}
+	}
+
+	runBoost(config, boostService)
 	c.Start()
 	utils.PrintNextExecution(c)
 
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if !config.DryRun {
+		go boostService.RunActivationScheduler(ctx)
+	}
+	if config.EventDriven {
+		watcher := services.NewBlockWatcher(config, &db, &ethRepository, func(ctx context.Context, operatorAddresses []common.Address) {
+			runBoostOperators(ctx, config, boostService, operatorAddresses)
+		})
+		go watcher.Run(ctx)
+	}
+
 	// Setup signal handling for graceful shutdown
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
@@ -69,5 +116,95 @@ func main() {
 
 	// Cleanup
 	log.Println("Shutting down gracefully...")
+	cancel()
 	c.Stop()
 }
+
+func runBoost(config *config.Config, boostService services.BoostService) {
+	if config.DryRun {
+		plan, err := boostService.Plan(context.Background())
+		if err != nil {
+			log.Printf("Boost plan failed, retrying on next schedule: %v", err)
+			return
+		}
+		plan.Log()
+		return
+	}
+
+	report, err := boostService.BoostValidator(context.Background())
+	if err != nil {
+		log.Printf("Boost run failed, retrying on next schedule: %v", err)
+		return
+	}
+	report.Log()
+}
+
+// runBoostOperators boosts only the validators of operators that just received BGT.
+func runBoostOperators(ctx context.Context, config *config.Config, boostService services.BoostService, operatorAddresses []common.Address) {
+	log.Printf("BGT received by %d operators, running boost for their validators", len(operatorAddresses))
+	if config.DryRun {
+		plan, err := boostService.PlanOperators(ctx, operatorAddresses)
+		if err != nil {
+			log.Printf("Boost plan failed, retrying on next schedule: %v", err)
+			return
+		}
+		plan.Log()
+		return
+	}
+
+	report, err := boostService.BoostOperators(ctx, operatorAddresses)
+	if err != nil {
+		log.Printf("Boost run failed, retrying on next schedule: %v", err)
+		return
+	}
+	report.Log()
+}
+
+func runReconcile(config *config.Config, boostService services.BoostService) {
+	reconciliation, err := boostService.Reconcile(context.Background(), config.ReconcileCorrect)
+	if err != nil {
+		log.Printf("Reconciliation failed, retrying on next schedule: %v", err)
+		return
+	}
+	reconciliation.Log()
+}
+
+func runVerifyTransactions(boostService services.BoostService) {
+	if err := boostService.VerifyTransactions(context.Background()); err != nil {
+		log.Printf("Transaction verification failed, retrying on next schedule: %v", err)
+	}
+}
+
+// runBackfillCommand indexes boost events from the command line:
+//
+//	main backfill [-from <block>] [-to <block>]
+func runBackfillCommand(args []string, boostService services.BoostService) {
+	flags := flag.NewFlagSet("backfill", flag.ExitOnError)
+	from := flags.Uint64("from", 0, "first block to index (default: after the checkpoint)")
+	to := flags.Uint64("to", 0, "last block to index (default: latest block)")
+	flags.Parse(args)
+
+	var fromBlock, toBlock *uint64
+	flags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "from":
+			fromBlock = from
+		case "to":
+			toBlock = to
+		}
+	})
+	report, err := boostService.Backfill(context.Background(), fromBlock, toBlock)
+	if err != nil {
+		panic(fmt.Sprintf("backfill failed: %s", err))
+	}
+	log.Printf("Backfill of blocks %d-%d found %d boost events, stored %d", report.FromBlock, report.ToBlock, report.Logs, report.Stored)
+}
+
+func runBackfill(boostService services.BoostService) {
+	report, err := boostService.Backfill(context.Background(), nil, nil)
+	if err != nil {
+		log.Printf("Backfill failed, retrying on next schedule: %v", err)
+		return
+	}
+	log.Printf("Backfill of blocks %d-%d found %d boost events, stored %d", report.FromBlock, report.ToBlock, report.Logs, report.Stored)
+}
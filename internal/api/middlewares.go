@@ -3,6 +3,7 @@ package api
 import (
 	"bgt_boost/internal/config"
 	"bgt_boost/internal/repository"
+	"bgt_boost/internal/services"
 
 	"github.com/gin-gonic/gin"
 )
@@ -14,6 +15,13 @@ func DatabaseMiddleware(dbRepository *repository.DbRepository) gin.HandlerFunc {
 	}
 }
 
+func BoostServiceMiddleware(boostService *services.BoostService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set("boostService", boostService)
+		c.Next()
+	}
+}
+
 func AdminMiddleware(config *config.Config) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		apiKey := c.GetHeader("X-API-Key")
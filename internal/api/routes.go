@@ -1,10 +1,16 @@
 package api
 
 import (
+	"bgt_boost/internal/models"
 	"bgt_boost/internal/repository"
+	"bgt_boost/internal/services"
+	"context"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -20,6 +26,7 @@ func (s *Server) RegisterRoutes() http.Handler {
 	r := gin.Default()
 	r.Use(cors.Default())
 	r.Use(DatabaseMiddleware(s.dbRepository))
+	r.Use(BoostServiceMiddleware(s.boostService))
 
 	// Public routes
 	r.GET("/", s.HelloWorldHandler)
@@ -32,6 +39,22 @@ func (s *Server) RegisterRoutes() http.Handler {
 		admin.POST("/validators", AddValidator)
 		admin.PUT("/validators/:pubkey", UpdateValidator)
 		admin.DELETE("/validators/:pubkey", DeleteValidator)
+		admin.GET("/validators/:pubkey/drop", GetDropBoostRequests)
+		admin.POST("/validators/:pubkey/drop", AddDropBoostRequest)
+		admin.POST("/validators/:pubkey/queue/cancel", CancelBoost)
+		admin.POST("/validators/:pubkey/drop/cancel", CancelDropBoost)
+		admin.GET("/operators", GetOperators)
+		admin.POST("/operators", AddOperator)
+		admin.GET("/operators/:address", GetOperator)
+		admin.PUT("/operators/:address", UpdateOperator)
+		admin.DELETE("/operators/:address", DeleteOperator)
+		admin.GET("/operators/:address/balances", GetOperatorBalances)
+		admin.GET("/operators/:address/history", GetOperatorHistory)
+		admin.PUT("/operators/:address/allocations", UpdateAllocations)
+		admin.GET("/plan", GetPlan)
+		admin.GET("/activations/upcoming", GetUpcomingActivations)
+		admin.GET("/reconcile", GetReconciliation)
+		admin.POST("/reconcile", Reconcile)
 	}
 
 	return r
@@ -82,6 +105,13 @@ func AddValidator(c *gin.Context) {
 		BadRequestResponse(c, "Validator already exists")
 		return
 	}
+	if !checkOperator(c, dbRepository, body.OperatorAddress) {
+		return
+	}
+	body.OperatorAddress = common.HexToAddress(body.OperatorAddress).Hex()
+	if !checkAllocation(c, dbRepository, []models.Validator{body}, "") {
+		return
+	}
 
 	err = (*dbRepository).AddValidator(c.Request.Context(), body)
 	if err != nil {
@@ -116,11 +146,23 @@ func UpdateValidator(c *gin.Context) {
 		return
 	}
 	if body.OperatorAddress != nil {
-		validator.OperatorAddress = *body.OperatorAddress
+		if !checkOperator(c, dbRepository, *body.OperatorAddress) {
+			return
+		}
+		validator.OperatorAddress = common.HexToAddress(*body.OperatorAddress).Hex()
 	}
 	if body.BoostThreshold != nil {
 		validator.BoostThreshold = *body.BoostThreshold
 	}
+	if body.AllocationPercentage != nil {
+		validator.AllocationPercentage = *body.AllocationPercentage
+	}
+	if body.MaxBoost != nil {
+		validator.MaxBoost = *body.MaxBoost
+	}
+	if !checkAllocation(c, dbRepository, []models.Validator{validator}, "") {
+		return
+	}
 	err = (*dbRepository).UpdateValidator(c.Request.Context(), c.Param("pubkey"), validator)
 	if err != nil {
 		log.Printf("Error updating validator: %v", err)
@@ -148,6 +190,9 @@ func DeleteValidator(c *gin.Context) {
 		BadRequestResponse(c, "Validator does not exist")
 		return
 	}
+	if !checkAllocation(c, dbRepository, nil, pubkey) {
+		return
+	}
 	err = (*dbRepository).DeleteValidator(c.Request.Context(), pubkey)
 	if err != nil {
 		log.Printf("Error deleting validator: %v", err)
@@ -156,3 +201,466 @@ func DeleteValidator(c *gin.Context) {
 	}
 	SuccessResponse(c, gin.H{"message": "Validator deleted successfully"})
 }
+
+func UpdateAllocations(c *gin.Context) {
+	dbRepository, ok := c.MustGet("dbRepository").(*repository.DbRepository)
+	if !ok {
+		log.Println("Error getting dbRepository")
+		InternalServerErrorResponse(c, "Internal server error")
+		return
+	}
+	body, err := ValidateUpdateAllocationsRequest(c)
+	if err != nil {
+		UnprocessableEntityResponse(c, err.Error())
+		return
+	}
+	validators, err := (*dbRepository).GetValidators(c.Request.Context())
+	if err != nil {
+		log.Printf("Error getting validators: %v", err)
+		InternalServerErrorResponse(c, "Internal server error")
+		return
+	}
+
+	operatorAddress := common.HexToAddress(c.Param("address"))
+	var saved []models.Validator
+	for _, validator := range validators {
+		if common.HexToAddress(validator.OperatorAddress) != operatorAddress {
+			continue
+		}
+		percentage, ok := body.Allocations[validator.Pubkey]
+		if !ok {
+			BadRequestResponse(c, fmt.Sprintf("Missing allocation for validator %s", validator.Pubkey))
+			return
+		}
+		validator.AllocationPercentage = percentage
+		saved = append(saved, validator)
+	}
+	if len(saved) != len(body.Allocations) {
+		BadRequestResponse(c, "Allocations contain validators that do not belong to this operator")
+		return
+	}
+	if !checkAllocation(c, dbRepository, saved, "") {
+		return
+	}
+
+	for _, validator := range saved {
+		if err := (*dbRepository).UpdateValidator(c.Request.Context(), validator.Pubkey, validator); err != nil {
+			log.Printf("Error updating validator: %v", err)
+			InternalServerErrorResponse(c, "Internal server error")
+			return
+		}
+	}
+	SuccessResponse(c, gin.H{"message": "Allocations updated successfully"})
+}
+
+func GetOperators(c *gin.Context) {
+	dbRepository, ok := c.MustGet("dbRepository").(*repository.DbRepository)
+	if !ok {
+		log.Println("Error getting dbRepository")
+		InternalServerErrorResponse(c, "Internal server error")
+		return
+	}
+	operators, err := (*dbRepository).GetOperators(c.Request.Context())
+	if err != nil {
+		log.Printf("Error getting operators: %v", err)
+		InternalServerErrorResponse(c, "Internal server error")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"operators": operators})
+}
+
+func GetOperator(c *gin.Context) {
+	dbRepository, ok := c.MustGet("dbRepository").(*repository.DbRepository)
+	if !ok {
+		log.Println("Error getting dbRepository")
+		InternalServerErrorResponse(c, "Internal server error")
+		return
+	}
+	operator, err := (*dbRepository).GetOperator(c.Request.Context(), c.Param("address"))
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			BadRequestResponse(c, "Operator does not exist")
+			return
+		}
+		log.Printf("Error getting operator: %v", err)
+		InternalServerErrorResponse(c, "Internal server error")
+		return
+	}
+	SuccessResponse(c, operator)
+}
+
+func AddOperator(c *gin.Context) {
+	dbRepository, ok := c.MustGet("dbRepository").(*repository.DbRepository)
+	if !ok {
+		log.Println("Error getting dbRepository")
+		InternalServerErrorResponse(c, "Internal server error")
+		return
+	}
+	body, err := ValidateAddOperatorRequest(c)
+	if err != nil {
+		UnprocessableEntityResponse(c, err.Error())
+		return
+	}
+	exists, err := (*dbRepository).DoesOperatorExist(c.Request.Context(), body.Address)
+	if err != nil {
+		log.Printf("Error checking if operator exists: %v", err)
+		InternalServerErrorResponse(c, "Internal server error")
+		return
+	}
+	if exists {
+		BadRequestResponse(c, "Operator already exists")
+		return
+	}
+
+	err = (*dbRepository).AddOperator(c.Request.Context(), body)
+	if err != nil {
+		log.Printf("Error adding operator: %v", err)
+		InternalServerErrorResponse(c, "Internal server error")
+		return
+	}
+	SuccessResponse(c, gin.H{"message": "Operator added successfully"})
+}
+
+func UpdateOperator(c *gin.Context) {
+	dbRepository, ok := c.MustGet("dbRepository").(*repository.DbRepository)
+	if !ok {
+		log.Println("Error getting dbRepository")
+		InternalServerErrorResponse(c, "Internal server error")
+		return
+	}
+	body, err := ValidateUpdateOperatorRequest(c)
+	if err != nil {
+		UnprocessableEntityResponse(c, err.Error())
+		return
+	}
+	operator, err := (*dbRepository).GetOperator(c.Request.Context(), c.Param("address"))
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			BadRequestResponse(c, "Operator does not exist")
+			return
+		}
+		log.Printf("Error getting operator: %v", err)
+		InternalServerErrorResponse(c, "Internal server error")
+		return
+	}
+	if body.Label != nil {
+		operator.Label = *body.Label
+	}
+	if body.SignerKey != nil {
+		operator.SignerKey = *body.SignerKey
+		if err := services.CheckSignerKey(operator); err != nil {
+			UnprocessableEntityResponse(c, err.Error())
+			return
+		}
+	}
+	if body.Enabled != nil {
+		operator.Enabled = *body.Enabled
+	}
+	if body.ReserveAmount != nil {
+		operator.ReserveAmount = *body.ReserveAmount
+	}
+	if body.ReservePercentage != nil {
+		operator.ReservePercentage = *body.ReservePercentage
+	}
+	if body.MaxBaseFeeGwei != nil {
+		operator.MaxBaseFeeGwei = *body.MaxBaseFeeGwei
+	}
+	if body.MaxActivationBaseFeeGwei != nil {
+		operator.MaxActivationBaseFeeGwei = *body.MaxActivationBaseFeeGwei
+	}
+	err = (*dbRepository).UpdateOperator(c.Request.Context(), operator.Address, operator)
+	if err != nil {
+		log.Printf("Error updating operator: %v", err)
+		InternalServerErrorResponse(c, "Internal server error")
+		return
+	}
+	SuccessResponse(c, gin.H{"message": "Operator updated successfully"})
+}
+
+func DeleteOperator(c *gin.Context) {
+	dbRepository, ok := c.MustGet("dbRepository").(*repository.DbRepository)
+	if !ok {
+		log.Println("Error getting dbRepository")
+		InternalServerErrorResponse(c, "Internal server error")
+		return
+	}
+	address := c.Param("address")
+	exists, err := (*dbRepository).DoesOperatorExist(c.Request.Context(), address)
+	if err != nil {
+		log.Printf("Error checking if operator exists: %v", err)
+		InternalServerErrorResponse(c, "Internal server error")
+		return
+	}
+	if !exists {
+		BadRequestResponse(c, "Operator does not exist")
+		return
+	}
+	validators, err := (*dbRepository).GetValidators(c.Request.Context())
+	if err != nil {
+		log.Printf("Error getting validators: %v", err)
+		InternalServerErrorResponse(c, "Internal server error")
+		return
+	}
+	for _, validator := range validators {
+		if common.HexToAddress(validator.OperatorAddress) == common.HexToAddress(address) {
+			BadRequestResponse(c, "Operator still has validators")
+			return
+		}
+	}
+	err = (*dbRepository).DeleteOperator(c.Request.Context(), address)
+	if err != nil {
+		log.Printf("Error deleting operator: %v", err)
+		InternalServerErrorResponse(c, "Internal server error")
+		return
+	}
+	SuccessResponse(c, gin.H{"message": "Operator deleted successfully"})
+}
+
+func GetOperatorBalances(c *gin.Context) {
+	boostService, ok := c.MustGet("boostService").(*services.BoostService)
+	if !ok {
+		log.Println("Error getting boostService")
+		InternalServerErrorResponse(c, "Internal server error")
+		return
+	}
+	balances, err := (*boostService).GetOperatorBalances(c.Request.Context(), c.Param("address"))
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			BadRequestResponse(c, "Operator does not exist")
+			return
+		}
+		log.Printf("Error getting operator balances: %v", err)
+		InternalServerErrorResponse(c, "Internal server error")
+		return
+	}
+	SuccessResponse(c, balances)
+}
+
+func GetOperatorHistory(c *gin.Context) {
+	dbRepository, ok := c.MustGet("dbRepository").(*repository.DbRepository)
+	if !ok {
+		log.Println("Error getting dbRepository")
+		InternalServerErrorResponse(c, "Internal server error")
+		return
+	}
+	if !checkOperator(c, dbRepository, c.Param("address")) {
+		return
+	}
+	history, err := (*dbRepository).GetOperatorHistory(c.Request.Context(), c.Param("address"))
+	if err != nil {
+		log.Printf("Error getting operator history: %v", err)
+		InternalServerErrorResponse(c, "Internal server error")
+		return
+	}
+	SuccessResponse(c, history)
+}
+
+// checkOperator writes an error response and returns false when the operator address does not belong to an
+// existing operator.
+func checkOperator(c *gin.Context, dbRepository *repository.DbRepository, address string) bool {
+	exists, err := (*dbRepository).DoesOperatorExist(c.Request.Context(), address)
+	if err != nil {
+		log.Printf("Error checking if operator exists: %v", err)
+		InternalServerErrorResponse(c, "Internal server error")
+		return false
+	}
+	if !exists {
+		BadRequestResponse(c, "Operator does not exist")
+		return false
+	}
+	return true
+}
+
+// checkAllocation writes an error response and returns false when the change would leave an operator with
+// allocation percentages that do not add up to 100%.
+func checkAllocation(c *gin.Context, dbRepository *repository.DbRepository, saved []models.Validator, removed string) bool {
+	validators, err := (*dbRepository).GetValidators(c.Request.Context())
+	if err != nil {
+		log.Printf("Error getting validators: %v", err)
+		InternalServerErrorResponse(c, "Internal server error")
+		return false
+	}
+	if err := ValidateAllocation(validators, saved, removed); err != nil {
+		UnprocessableEntityResponse(c, err.Error())
+		return false
+	}
+	return true
+}
+
+func GetDropBoostRequests(c *gin.Context) {
+	dbRepository, ok := c.MustGet("dbRepository").(*repository.DbRepository)
+	if !ok {
+		log.Println("Error getting dbRepository")
+		InternalServerErrorResponse(c, "Internal server error")
+		return
+	}
+	requests, err := (*dbRepository).GetDropBoostRequests(c.Request.Context(), c.Param("pubkey"), c.Query("status"))
+	if err != nil {
+		log.Printf("Error getting drop boost requests: %v", err)
+		InternalServerErrorResponse(c, "Internal server error")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"dropBoostRequests": requests})
+}
+
+func AddDropBoostRequest(c *gin.Context) {
+	dbRepository, ok := c.MustGet("dbRepository").(*repository.DbRepository)
+	if !ok {
+		log.Println("Error getting dbRepository")
+		InternalServerErrorResponse(c, "Internal server error")
+		return
+	}
+	body, err := ValidateDropBoostRequest(c)
+	if err != nil {
+		UnprocessableEntityResponse(c, err.Error())
+		return
+	}
+	validator, err := (*dbRepository).GetValidator(c.Request.Context(), c.Param("pubkey"))
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			BadRequestResponse(c, "Validator does not exist")
+			return
+		}
+		log.Printf("Error getting validator: %v", err)
+		InternalServerErrorResponse(c, "Internal server error")
+		return
+	}
+
+	err = (*dbRepository).AddDropBoostRequest(c.Request.Context(), models.DropBoostRequest{
+		ValidatorPubkey: validator.Pubkey,
+		OperatorAddress: validator.OperatorAddress,
+		Amount:          body.Amount,
+		Status:          models.DropBoostRequestStatusPending,
+	})
+	if err != nil {
+		log.Printf("Error adding drop boost request: %v", err)
+		InternalServerErrorResponse(c, "Internal server error")
+		return
+	}
+	SuccessResponse(c, gin.H{"message": "Drop boost requested successfully"})
+}
+
+func CancelBoost(c *gin.Context) {
+	boostService, ok := c.MustGet("boostService").(*services.BoostService)
+	if !ok {
+		log.Println("Error getting boostService")
+		InternalServerErrorResponse(c, "Internal server error")
+		return
+	}
+	amount, err := ValidateCancelRequest(c)
+	if err != nil {
+		UnprocessableEntityResponse(c, err.Error())
+		return
+	}
+	// The cancel is waited for and recorded after the response, so it must outlive the request
+	pending, err := (*boostService).CancelBoost(context.WithoutCancel(c.Request.Context()), c.Param("pubkey"), amount)
+	if err != nil {
+		handleCancelError(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"message": "Boost cancel sent", "transactionHash": pending.TransactionHash, "amount": pending.Amount})
+}
+
+func CancelDropBoost(c *gin.Context) {
+	boostService, ok := c.MustGet("boostService").(*services.BoostService)
+	if !ok {
+		log.Println("Error getting boostService")
+		InternalServerErrorResponse(c, "Internal server error")
+		return
+	}
+	amount, err := ValidateCancelRequest(c)
+	if err != nil {
+		UnprocessableEntityResponse(c, err.Error())
+		return
+	}
+	pending, err := (*boostService).CancelDropBoost(context.WithoutCancel(c.Request.Context()), c.Param("pubkey"), amount)
+	if err != nil {
+		handleCancelError(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"message": "Drop boost cancel sent", "transactionHash": pending.TransactionHash, "amount": pending.Amount})
+}
+
+func GetPlan(c *gin.Context) {
+	boostService, ok := c.MustGet("boostService").(*services.BoostService)
+	if !ok {
+		log.Println("Error getting boostService")
+		InternalServerErrorResponse(c, "Internal server error")
+		return
+	}
+	plan, err := (*boostService).Plan(c.Request.Context())
+	if err != nil {
+		log.Printf("Error building plan: %v", err)
+		InternalServerErrorResponse(c, "Internal server error")
+		return
+	}
+	SuccessResponse(c, plan)
+}
+
+func GetUpcomingActivations(c *gin.Context) {
+	boostService, ok := c.MustGet("boostService").(*services.BoostService)
+	if !ok {
+		log.Println("Error getting boostService")
+		InternalServerErrorResponse(c, "Internal server error")
+		return
+	}
+	activations, err := (*boostService).UpcomingActivations(c.Request.Context())
+	if err != nil {
+		log.Printf("Error getting upcoming activations: %v", err)
+		InternalServerErrorResponse(c, "Internal server error")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"activations": activations})
+}
+
+// GetReconciliation reports how the database differs from the BGT contract.
+func GetReconciliation(c *gin.Context) {
+	boostService, ok := c.MustGet("boostService").(*services.BoostService)
+	if !ok {
+		log.Println("Error getting boostService")
+		InternalServerErrorResponse(c, "Internal server error")
+		return
+	}
+	reconciliation, err := (*boostService).Reconcile(c.Request.Context(), false)
+	if err != nil {
+		log.Printf("Error reconciling: %v", err)
+		InternalServerErrorResponse(c, "Internal server error")
+		return
+	}
+	SuccessResponse(c, reconciliation)
+}
+
+// Reconcile starts a reconciliation that writes correcting records. It waits for every operator's running boosts,
+// which can take longer than a request, so it runs in the background and logs its result.
+func Reconcile(c *gin.Context) {
+	boostService, ok := c.MustGet("boostService").(*services.BoostService)
+	if !ok {
+		log.Println("Error getting boostService")
+		InternalServerErrorResponse(c, "Internal server error")
+		return
+	}
+	ctx := context.WithoutCancel(c.Request.Context())
+	go func() {
+		reconciliation, err := (*boostService).Reconcile(ctx, true)
+		if err != nil {
+			log.Printf("Error reconciling: %v", err)
+			return
+		}
+		reconciliation.Log()
+	}()
+	c.JSON(http.StatusAccepted, gin.H{"message": "Reconciliation started"})
+}
+
+func handleCancelError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrValidatorDoesNotExist):
+		BadRequestResponse(c, "Validator does not exist")
+	case errors.Is(err, services.ErrNothingToCancel),
+		errors.Is(err, services.ErrCancelAmountTooHigh),
+		errors.Is(err, services.ErrInvalidCancelAmount):
+		BadRequestResponse(c, err.Error())
+	default:
+		log.Printf("Error cancelling: %v", err)
+		InternalServerErrorResponse(c, "Internal server error")
+	}
+}
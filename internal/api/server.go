@@ -3,6 +3,7 @@ package api
 import (
 	"bgt_boost/internal/config"
 	"bgt_boost/internal/repository"
+	"bgt_boost/internal/services"
 	"fmt"
 	"net/http"
 	"time"
@@ -12,12 +13,14 @@ import (
 
 type Server struct {
 	dbRepository *repository.DbRepository
+	boostService *services.BoostService
 	config       *config.Config
 }
 
-func NewServer(config *config.Config, dbRepository *repository.DbRepository) *http.Server {
+func NewServer(config *config.Config, dbRepository *repository.DbRepository, boostService *services.BoostService) *http.Server {
 	NewServer := &Server{
 		dbRepository: dbRepository,
+		boostService: boostService,
 		config:       config,
 	}
 
@@ -2,9 +2,13 @@ package api
 
 import (
 	"bgt_boost/internal/models"
+	"bgt_boost/internal/services"
 	"errors"
+	"fmt"
+	"io"
 	"math/big"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator"
 )
@@ -21,8 +25,14 @@ func validateStruct(s interface{}) error {
 }
 
 type UpdateValidatorRequest struct {
-	OperatorAddress *string `json:"operatorAddress"`
-	BoostThreshold  *string `json:"boostThreshold"`
+	OperatorAddress      *string `json:"operatorAddress"`
+	BoostThreshold       *string `json:"boostThreshold"`
+	AllocationPercentage *uint   `json:"allocationPercentage" validate:"omitempty,max=100"`
+	MaxBoost             *string `json:"maxBoost"`
+}
+
+type UpdateAllocationsRequest struct {
+	Allocations map[string]uint `json:"allocations" validate:"required"`
 }
 
 func ValidateAddValidatorRequest(c *gin.Context) (models.Validator, error) {
@@ -41,6 +51,9 @@ func ValidateAddValidatorRequest(c *gin.Context) (models.Validator, error) {
 	if boostThreshold.Cmp(big.NewInt(1e18)) < 0 {
 		return models.Validator{}, errors.New("boostThreshold should be greater than 1e18")
 	}
+	if err := validateMaxBoost(body.MaxBoost); err != nil {
+		return models.Validator{}, err
+	}
 	return body, nil
 }
 
@@ -53,12 +66,228 @@ func ValidateUpdateValidatorRequest(c *gin.Context) (UpdateValidatorRequest, err
 		return UpdateValidatorRequest{}, err
 	}
 
-	boostThreshold, ok := big.NewInt(0).SetString(*body.BoostThreshold, 10)
+	if body.BoostThreshold != nil {
+		boostThreshold, ok := big.NewInt(0).SetString(*body.BoostThreshold, 10)
+		if !ok {
+			return UpdateValidatorRequest{}, errors.New("invalid boostThreshold")
+		}
+		if boostThreshold.Cmp(big.NewInt(1e18)) < 0 {
+			return UpdateValidatorRequest{}, errors.New("boostThreshold should be greater than 1e18")
+		}
+	}
+	if body.MaxBoost != nil {
+		if err := validateMaxBoost(*body.MaxBoost); err != nil {
+			return UpdateValidatorRequest{}, err
+		}
+	}
+	return body, nil
+}
+
+// validateMaxBoost accepts an empty maxBoost, which means the validator has no cap.
+func validateMaxBoost(maxBoost string) error {
+	if maxBoost == "" {
+		return nil
+	}
+	value, ok := big.NewInt(0).SetString(maxBoost, 10)
 	if !ok {
-		return UpdateValidatorRequest{}, errors.New("invalid boostThreshold")
+		return errors.New("invalid maxBoost")
 	}
-	if boostThreshold.Cmp(big.NewInt(1e18)) < 0 {
-		return UpdateValidatorRequest{}, errors.New("boostThreshold should be greater than 1e18")
+	if value.Sign() <= 0 {
+		return errors.New("maxBoost should be greater than 0")
+	}
+	return nil
+}
+
+func ValidateUpdateAllocationsRequest(c *gin.Context) (UpdateAllocationsRequest, error) {
+	var body UpdateAllocationsRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		return UpdateAllocationsRequest{}, err
+	}
+	if err := validateStruct(body); err != nil {
+		return UpdateAllocationsRequest{}, err
+	}
+	for pubkey, percentage := range body.Allocations {
+		if percentage > 100 {
+			return UpdateAllocationsRequest{}, fmt.Errorf("allocation percentage of %s should not be greater than 100", pubkey)
+		}
+	}
+	return body, nil
+}
+
+// ValidateAllocation checks that every operator touched by a change still has a valid allocation once the saved
+// validators are written and the validator with the removed pubkey is deleted.
+func ValidateAllocation(validators []models.Validator, saved []models.Validator, removed string) error {
+	affected := make(map[common.Address]bool)
+	savedByPubkey := make(map[string]models.Validator)
+	for _, validator := range saved {
+		savedByPubkey[validator.Pubkey] = validator
+		affected[common.HexToAddress(validator.OperatorAddress)] = true
+	}
+
+	result := make([]models.Validator, 0, len(validators)+len(saved))
+	for _, validator := range validators {
+		savedValidator, isSaved := savedByPubkey[validator.Pubkey]
+		if validator.Pubkey == removed || isSaved {
+			affected[common.HexToAddress(validator.OperatorAddress)] = true
+		}
+		switch {
+		case validator.Pubkey == removed:
+		case isSaved:
+			result = append(result, savedValidator)
+			delete(savedByPubkey, validator.Pubkey)
+		default:
+			result = append(result, validator)
+		}
+	}
+	for _, validator := range saved {
+		if _, ok := savedByPubkey[validator.Pubkey]; ok {
+			result = append(result, validator)
+		}
+	}
+
+	for _, operatorValidators := range services.GroupByOperator(result) {
+		if !affected[common.HexToAddress(operatorValidators[0].OperatorAddress)] {
+			continue
+		}
+		if err := services.CheckAllocation(operatorValidators); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type DropBoostRequest struct {
+	Amount string `json:"amount" validate:"required"`
+}
+
+func ValidateDropBoostRequest(c *gin.Context) (DropBoostRequest, error) {
+	var body DropBoostRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		return DropBoostRequest{}, err
+	}
+	if err := validateStruct(body); err != nil {
+		return DropBoostRequest{}, err
+	}
+
+	amount, ok := big.NewInt(0).SetString(body.Amount, 10)
+	if !ok {
+		return DropBoostRequest{}, errors.New("invalid amount")
+	}
+	if amount.Sign() <= 0 {
+		return DropBoostRequest{}, errors.New("amount should be greater than 0")
 	}
 	return body, nil
 }
+
+type CancelRequest struct {
+	Amount *string `json:"amount"`
+}
+
+// ValidateCancelRequest returns the amount to cancel, or nil when the whole queue should be cancelled.
+func ValidateCancelRequest(c *gin.Context) (*big.Int, error) {
+	var body CancelRequest
+	if err := c.ShouldBindJSON(&body); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	if body.Amount == nil {
+		return nil, nil
+	}
+
+	amount, ok := big.NewInt(0).SetString(*body.Amount, 10)
+	if !ok {
+		return nil, errors.New("invalid amount")
+	}
+	if amount.Sign() <= 0 {
+		return nil, errors.New("amount should be greater than 0")
+	}
+	return amount, nil
+}
+
+type AddOperatorRequest struct {
+	Address                  string  `json:"address" validate:"required"`
+	Label                    string  `json:"label"`
+	SignerKey                string  `json:"signerKey"`
+	Enabled                  *bool   `json:"enabled"`
+	ReserveAmount            string  `json:"reserveAmount"`
+	ReservePercentage        uint    `json:"reservePercentage" validate:"max=100"`
+	MaxBaseFeeGwei           float64 `json:"maxBaseFeeGwei" validate:"min=0"`
+	MaxActivationBaseFeeGwei float64 `json:"maxActivationBaseFeeGwei" validate:"min=0"`
+}
+
+type UpdateOperatorRequest struct {
+	Label                    *string  `json:"label"`
+	SignerKey                *string  `json:"signerKey"`
+	Enabled                  *bool    `json:"enabled"`
+	ReserveAmount            *string  `json:"reserveAmount"`
+	ReservePercentage        *uint    `json:"reservePercentage" validate:"omitempty,max=100"`
+	MaxBaseFeeGwei           *float64 `json:"maxBaseFeeGwei" validate:"omitempty,min=0"`
+	MaxActivationBaseFeeGwei *float64 `json:"maxActivationBaseFeeGwei" validate:"omitempty,min=0"`
+}
+
+// ValidateAddOperatorRequest returns the operator to add. Operators are enabled unless the request says otherwise.
+func ValidateAddOperatorRequest(c *gin.Context) (models.Operator, error) {
+	var body AddOperatorRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		return models.Operator{}, err
+	}
+	if err := validateStruct(body); err != nil {
+		return models.Operator{}, err
+	}
+
+	if !common.IsHexAddress(body.Address) {
+		return models.Operator{}, errors.New("invalid address")
+	}
+	if err := validateReserveAmount(body.ReserveAmount); err != nil {
+		return models.Operator{}, err
+	}
+	enabled := true
+	if body.Enabled != nil {
+		enabled = *body.Enabled
+	}
+	operator := models.Operator{
+		Address:                  common.HexToAddress(body.Address).Hex(),
+		Label:                    body.Label,
+		SignerKey:                body.SignerKey,
+		Enabled:                  enabled,
+		ReserveAmount:            body.ReserveAmount,
+		ReservePercentage:        body.ReservePercentage,
+		MaxBaseFeeGwei:           body.MaxBaseFeeGwei,
+		MaxActivationBaseFeeGwei: body.MaxActivationBaseFeeGwei,
+	}
+	if err := services.CheckSignerKey(operator); err != nil {
+		return models.Operator{}, err
+	}
+	return operator, nil
+}
+
+func ValidateUpdateOperatorRequest(c *gin.Context) (UpdateOperatorRequest, error) {
+	var body UpdateOperatorRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		return UpdateOperatorRequest{}, err
+	}
+	if err := validateStruct(body); err != nil {
+		return UpdateOperatorRequest{}, err
+	}
+
+	if body.ReserveAmount != nil {
+		if err := validateReserveAmount(*body.ReserveAmount); err != nil {
+			return UpdateOperatorRequest{}, err
+		}
+	}
+	return body, nil
+}
+
+// validateReserveAmount accepts an empty reserveAmount, which means the operator keeps no absolute reserve.
+func validateReserveAmount(reserveAmount string) error {
+	if reserveAmount == "" {
+		return nil
+	}
+	amount, ok := big.NewInt(0).SetString(reserveAmount, 10)
+	if !ok {
+		return errors.New("invalid reserveAmount")
+	}
+	if amount.Sign() < 0 {
+		return errors.New("reserveAmount should not be negative")
+	}
+	return nil
+}
@@ -33,12 +33,55 @@ type Config struct {
 	Db          DbConfig
 	AdminAPIKey string
 
-	RPC_URL       string
+	RPC_URLS      []string
 	Web3SignerURL string
 	BGTContract   Contract
 	GasLimit      int
+	UseMulticall  bool
 
-	CronSchedule string
+	RPCMaxLagBlocks   int
+	RPCCheckSeconds   int
+	RPCBroadcastCount int
+
+	GasBufferPercent int
+	MethodGasLimits  map[string]uint64
+
+	ConfirmationBlocks     int
+	ReorgCheckBlocks       int
+	ReorgCheckSchedule     string
+	InclusionTimeoutBlocks int
+	MaxInclusionTimeouts   int
+	FeeBumpPercent         int
+	MaxFeeCapGwei          float64
+
+	BaseFeeMultiplier float64
+	TipSource         string
+	TipPercentile     float64
+	FeeHistoryBlocks  int
+	MinTipGwei        float64
+	MaxTipGwei        float64
+
+	MaxBaseFeeGwei           float64
+	MaxActivationBaseFeeGwei float64
+	MaxFeeToBoostRatio       float64
+
+	QueueResetPolicy string
+
+	CronSchedule     string
+	DryRun           bool
+	BoostConcurrency int
+
+	EventDriven          bool
+	EventDebounceSeconds int
+	EventPollSeconds     int
+	EventMaxWaitSeconds  int
+
+	ReconcileSchedule string
+	ReconcileCorrect  bool
+
+	IndexerStartBlock uint64
+	IndexerChunkSize  int
+	IndexerSchedule   string
 }
 
 func LoadConfig() *Config {
@@ -64,15 +107,64 @@ func LoadConfig() *Config {
 		},
 		AdminAPIKey: getEnvString("ADMIN_API_KEY", nil),
 
-		RPC_URL:       getEnvString("RPC_URL", nil),
+		RPC_URLS:      getEnvURLs("RPC_URLS", "RPC_URL"),
 		Web3SignerURL: getEnvString("WEB3SIGNER_URL", nil),
 		BGTContract: Contract{
 			Address: common.HexToAddress(bgtContract),
 			ABI:     bgtABI,
 		},
-		GasLimit: getEnvInt("GAS_LIMIT", ptr(150000)),
+		GasLimit:     getEnvInt("GAS_LIMIT", ptr(150000)),
+		UseMulticall: getEnvBool("USE_MULTICALL", ptr(true)),
+
+		RPCMaxLagBlocks:   getEnvInt("RPC_MAX_LAG_BLOCKS", ptr(5)),
+		RPCCheckSeconds:   getEnvInt("RPC_CHECK_SECONDS", ptr(10)),
+		RPCBroadcastCount: getEnvInt("RPC_BROADCAST_COUNT", ptr(1)),
+
+		GasBufferPercent: getEnvInt("GAS_BUFFER_PERCENT", ptr(20)),
+		MethodGasLimits:  getEnvGasLimits("METHOD_GAS_LIMITS", ptr("multicall=600000")),
+
+		ConfirmationBlocks:     getEnvInt("CONFIRMATION_BLOCKS", ptr(2)),
+		ReorgCheckBlocks:       getEnvInt("REORG_CHECK_BLOCKS", ptr(100)),
+		ReorgCheckSchedule:     getEnvString("REORG_CHECK_SCHEDULE", ptr("30 * * * * *")),
+		InclusionTimeoutBlocks: getEnvInt("INCLUSION_TIMEOUT_BLOCKS", ptr(20)),
+		MaxInclusionTimeouts:   getEnvInt("MAX_INCLUSION_TIMEOUTS", ptr(10)),
+		FeeBumpPercent:         getEnvInt("FEE_BUMP_PERCENT", ptr(15)),
+		MaxFeeCapGwei:          getEnvFloat("MAX_FEE_CAP_GWEI", ptr(0.0)),
+
+		BaseFeeMultiplier: getEnvFloat("BASE_FEE_MULTIPLIER", ptr(2.0)),
+		TipSource:         getEnvOneOf("TIP_SOURCE", []string{"suggest", "fee_history"}, ptr("suggest")),
+		TipPercentile:     getEnvFloat("TIP_PERCENTILE", ptr(50.0)),
+		FeeHistoryBlocks:  getEnvInt("FEE_HISTORY_BLOCKS", ptr(20)),
+		MinTipGwei:        getEnvFloat("MIN_TIP_GWEI", ptr(0.0)),
+		MaxTipGwei:        getEnvFloat("MAX_TIP_GWEI", ptr(0.0)),
+
+		MaxBaseFeeGwei:           getEnvFloat("MAX_BASE_FEE_GWEI", ptr(0.0)),
+		MaxActivationBaseFeeGwei: getEnvFloat("MAX_ACTIVATION_BASE_FEE_GWEI", ptr(0.0)),
+		MaxFeeToBoostRatio:       getEnvFloat("MAX_FEE_TO_BOOST_RATIO", ptr(0.0)),
 
-		CronSchedule: getEnvString("CRON_SCHEDULE", ptr("0 */5 * * * *")),
+		QueueResetPolicy: getEnvOneOf("QUEUE_RESET_POLICY", []string{"hold", "reset", "auto"}, ptr("hold")),
+
+		CronSchedule:     getEnvString("CRON_SCHEDULE", ptr("0 */5 * * * *")),
+		DryRun:           getEnvBool("DRY_RUN", ptr(false)),
+		BoostConcurrency: getEnvInt("BOOST_CONCURRENCY", ptr(4)),
+
+		EventDriven:          getEnvBool("EVENT_DRIVEN", ptr(false)),
+		EventDebounceSeconds: getEnvInt("EVENT_DEBOUNCE_SECONDS", ptr(10)),
+		EventPollSeconds:     getEnvInt("EVENT_POLL_SECONDS", ptr(5)),
+		EventMaxWaitSeconds:  getEnvInt("EVENT_MAX_WAIT_SECONDS", ptr(60)),
+
+		ReconcileSchedule: getEnvString("RECONCILE_SCHEDULE", ptr("")),
+		ReconcileCorrect:  getEnvBool("RECONCILE_CORRECT", ptr(false)),
+
+		IndexerStartBlock: uint64(getEnvInt("INDEXER_START_BLOCK", ptr(0))),
+		IndexerChunkSize:  getEnvInt("INDEXER_CHUNK_SIZE", ptr(2000)),
+		IndexerSchedule:   getEnvString("INDEXER_SCHEDULE", ptr("")),
+	}
+	if config.TipPercentile < 0 || config.TipPercentile > 100 {
+		panic("Environment variable TIP_PERCENTILE must be between 0 and 100")
+	}
+	if config.MaxTipGwei > 0 && config.MinTipGwei > config.MaxTipGwei {
+		panic("Environment variable MIN_TIP_GWEI must not be above MAX_TIP_GWEI")
 	}
 	log.Println("✅ Config Loaded")
 	return &config
@@ -147,6 +239,80 @@ func getEnvInt(key string, defaultValue *int) int {
 	return *defaultValue
 }
 
+func getEnvFloat(key string, defaultValue *float64) float64 {
+	value := os.Getenv(key)
+	if value != "" {
+		floatValue, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			panic(fmt.Sprintf("Environment variable %s is not a valid number", key))
+		}
+		return floatValue
+	}
+	if defaultValue == nil {
+		panic(fmt.Sprintf("Environment variable %s is required", key))
+	}
+	return *defaultValue
+}
+
+func getEnvBool(key string, defaultValue *bool) bool {
+	value := os.Getenv(key)
+	if value != "" {
+		boolValue, err := strconv.ParseBool(value)
+		if err != nil {
+			panic(fmt.Sprintf("Environment variable %s is not a valid boolean", key))
+		}
+		return boolValue
+	}
+	if defaultValue == nil {
+		panic(fmt.Sprintf("Environment variable %s is required", key))
+	}
+	return *defaultValue
+}
+
+// getEnvOneOf reads a value that must be one of allowed.
+func getEnvOneOf(key string, allowed []string, defaultValue *string) string {
+	value := getEnvString(key, defaultValue)
+	for _, candidate := range allowed {
+		if value == candidate {
+			return value
+		}
+	}
+	panic(fmt.Sprintf("Environment variable %s must be one of %s", key, strings.Join(allowed, ", ")))
+}
+
+// getEnvURLs reads a comma-separated list of URLs, falling back to the single URL in fallbackKey.
+func getEnvURLs(key string, fallbackKey string) []string {
+	var urls []string
+	for _, url := range strings.Split(getEnvString(key, ptr("")), ",") {
+		if url = strings.TrimSpace(url); url != "" {
+			urls = append(urls, url)
+		}
+	}
+	if len(urls) == 0 {
+		urls = append(urls, getEnvString(fallbackKey, nil))
+	}
+	return urls
+}
+
+// getEnvGasLimits reads gas limits per contract method written as method=gas pairs separated by commas.
+func getEnvGasLimits(key string, defaultValue *string) map[string]uint64 {
+	value := getEnvString(key, defaultValue)
+	limits := make(map[string]uint64)
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		method, gas, found := strings.Cut(pair, "=")
+		gasLimit, err := strconv.ParseUint(strings.TrimSpace(gas), 10, 64)
+		if !found || err != nil {
+			panic(fmt.Sprintf("Environment variable %s has an invalid gas limit %q", key, pair))
+		}
+		limits[strings.TrimSpace(method)] = gasLimit
+	}
+	return limits
+}
+
 func ptr[T any](v T) *T {
 	return &v
 }
@@ -0,0 +1,34 @@
+package config
+
+import (
+	"maps"
+	"testing"
+)
+
+func TestGetEnvGasLimits(t *testing.T) {
+	for value, want := range map[string]map[string]uint64{
+		"":                                     {"multicall": 600000},
+		"queueBoost=120000":                    {"queueBoost": 120000},
+		"multicall=800000,activateBoost=90000": {"multicall": 800000, "activateBoost": 90000},
+		" multicall = 800000 , ,dropBoost=70000,": {"multicall": 800000, "dropBoost": 70000},
+	} {
+		t.Setenv("METHOD_GAS_LIMITS", value)
+		if got := getEnvGasLimits("METHOD_GAS_LIMITS", ptr("multicall=600000")); !maps.Equal(got, want) {
+			t.Errorf("METHOD_GAS_LIMITS=%q: got %v, want %v", value, got, want)
+		}
+	}
+}
+
+func TestGetEnvGasLimitsPanicsOnInvalidValues(t *testing.T) {
+	for _, value := range []string{"multicall", "multicall=lots", "multicall=-1"} {
+		t.Setenv("METHOD_GAS_LIMITS", value)
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("METHOD_GAS_LIMITS=%q did not panic", value)
+				}
+			}()
+			getEnvGasLimits("METHOD_GAS_LIMITS", ptr("multicall=600000"))
+		}()
+	}
+}
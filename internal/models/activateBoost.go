@@ -1,15 +1,27 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
 
 type ActivateBoost struct {
-	Amount          string    `bson:"amount"`
-	ValidatorPubkey string    `bson:"validatorPubkey"`
-	OperatorAddress string    `bson:"operatorAddress"`
-	TransactionHash string    `bson:"transactionHash"`
-	BlockNumber     uint64    `bson:"blockNumber"`
-	BlockTimestamp  time.Time `bson:"blockTimestamp"`
-	Fee             float64   `bson:"fee"`
-	TransactionFrom string    `bson:"transactionFrom"`
-	ToContract      string    `bson:"toContract"`
+	Amount          string           `bson:"amount"`
+	ValidatorPubkey string           `bson:"validatorPubkey"`
+	OperatorAddress string           `bson:"operatorAddress"`
+	TransactionHash string           `bson:"transactionHash"`
+	BlockNumber     uint64           `bson:"blockNumber"`
+	BlockHash       string           `bson:"blockHash,omitempty"`
+	BlockTimestamp  time.Time        `bson:"blockTimestamp"`
+	Fee             float64          `bson:"fee"`
+	Fees            *TransactionFees `bson:"fees,omitempty"`
+	TransactionFrom string           `bson:"transactionFrom"`
+	ToContract      string           `bson:"toContract"`
+	BatchSize       int              `bson:"batchSize,omitempty"`
+	Source          string           `bson:"source,omitempty"`
+	LogIndex        *uint            `bson:"logIndex,omitempty"`
+
+	// QueueBoostIDs are the queue records this activation settled
+	QueueBoostIDs []primitive.ObjectID `bson:"queueBoostIds,omitempty"`
 }
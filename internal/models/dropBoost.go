@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+type DropBoost struct {
+	Amount          string           `bson:"amount"`
+	ValidatorPubkey string           `bson:"validatorPubkey"`
+	OperatorAddress string           `bson:"operatorAddress"`
+	TransactionHash string           `bson:"transactionHash"`
+	BlockNumber     uint64           `bson:"blockNumber"`
+	BlockTimestamp  time.Time        `bson:"blockTimestamp"`
+	Fee             float64          `bson:"fee"`
+	Fees            *TransactionFees `bson:"fees,omitempty"`
+	TransactionFrom string           `bson:"transactionFrom"`
+	ToContract      string           `bson:"toContract"`
+	BatchSize       int              `bson:"batchSize,omitempty"`
+	Source          string           `bson:"source,omitempty"`
+	LogIndex        *uint            `bson:"logIndex,omitempty"`
+}
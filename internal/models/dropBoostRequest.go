@@ -0,0 +1,21 @@
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+const (
+	DropBoostRequestStatusPending   = "pending"
+	DropBoostRequestStatusQueued    = "queued"
+	DropBoostRequestStatusDropped   = "dropped"
+	DropBoostRequestStatusCancelled = "cancelled"
+)
+
+type DropBoostRequest struct {
+	ID                    primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ValidatorPubkey       string             `bson:"validatorPubkey" json:"validatorPubkey"`
+	OperatorAddress       string             `bson:"operatorAddress" json:"operatorAddress"`
+	Amount                string             `bson:"amount" json:"amount"`
+	Status                string             `bson:"status" json:"status"`
+	QueueTransactionHash  string             `bson:"queueTransactionHash,omitempty" json:"queueTransactionHash,omitempty"`
+	DropTransactionHash   string             `bson:"dropTransactionHash,omitempty" json:"dropTransactionHash,omitempty"`
+	CancelTransactionHash string             `bson:"cancelTransactionHash,omitempty" json:"cancelTransactionHash,omitempty"`
+}
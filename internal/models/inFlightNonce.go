@@ -0,0 +1,8 @@
+package models
+
+// InFlightNonce is a nonce handed out to an operator transaction that is not known to be mined yet.
+type InFlightNonce struct {
+	OperatorAddress string `bson:"operatorAddress"`
+	Nonce           uint64 `bson:"nonce"`
+	TransactionHash string `bson:"transactionHash,omitempty"`
+}
@@ -0,0 +1,12 @@
+package models
+
+type Operator struct {
+	Address                  string  `bson:"address,unique" json:"address"`
+	Label                    string  `bson:"label" json:"label"`
+	SignerKey                string  `bson:"signerKey" json:"signerKey"`
+	Enabled                  bool    `bson:"enabled" json:"enabled"`
+	ReserveAmount            string  `bson:"reserveAmount" json:"reserveAmount"`
+	ReservePercentage        uint    `bson:"reservePercentage" json:"reservePercentage"`
+	MaxBaseFeeGwei           float64 `bson:"maxBaseFeeGwei" json:"maxBaseFeeGwei"`
+	MaxActivationBaseFeeGwei float64 `bson:"maxActivationBaseFeeGwei" json:"maxActivationBaseFeeGwei"`
+}
@@ -1,15 +1,42 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// QueueBoostStatusPending is waiting for the activation delay to pass
+	QueueBoostStatusPending = "pending"
+	// QueueBoostStatusSuperseded is still queued, but a later queueBoost restarted its activation delay
+	QueueBoostStatusSuperseded = "superseded"
+	QueueBoostStatusActivated  = "activated"
+	QueueBoostStatusCancelled  = "cancelled"
+)
 
 type QueueBoost struct {
-	ValidatorPubkey string    `bson:"validatorPubkey"`
-	OperatorAddress string    `bson:"operatorAddress"`
-	BlockNumber     uint64    `bson:"blockNumber"`
-	Amount          string    `bson:"amount"`
-	TransactionHash string    `bson:"transactionHash"`
-	BlockTimestamp  time.Time `bson:"blockTimestamp"`
-	Fee             float64   `bson:"fee"`
-	TransactionFrom string    `bson:"transactionFrom"`
-	ToContract      string    `bson:"toContract"`
+	ID              primitive.ObjectID `bson:"_id,omitempty"`
+	ValidatorPubkey string             `bson:"validatorPubkey"`
+	OperatorAddress string             `bson:"operatorAddress"`
+	BlockNumber     uint64             `bson:"blockNumber"`
+	BlockHash       string             `bson:"blockHash,omitempty"`
+	Amount          string             `bson:"amount"`
+	TransactionHash string             `bson:"transactionHash"`
+	BlockTimestamp  time.Time          `bson:"blockTimestamp"`
+	Fee             float64            `bson:"fee"`
+	Fees            *TransactionFees   `bson:"fees,omitempty"`
+	TransactionFrom string             `bson:"transactionFrom"`
+	ToContract      string             `bson:"toContract"`
+	BatchSize       int                `bson:"batchSize,omitempty"`
+	Source          string             `bson:"source,omitempty"`
+	LogIndex        *uint              `bson:"logIndex,omitempty"`
+
+	Status                   string `bson:"status"`
+	SupersedeTransactionHash string `bson:"supersedeTransactionHash,omitempty"`
+	ActivateTransactionHash  string `bson:"activateTransactionHash,omitempty"`
+	ActivateBlockNumber      uint64 `bson:"activateBlockNumber,omitempty"`
+	CancelTransactionHash    string `bson:"cancelTransactionHash,omitempty"`
+	// CancelledAmount is the part of Amount a partial cancel took out of the queue
+	CancelledAmount string `bson:"cancelledAmount,omitempty"`
 }
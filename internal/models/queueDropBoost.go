@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+type QueueDropBoost struct {
+	ValidatorPubkey string           `bson:"validatorPubkey"`
+	OperatorAddress string           `bson:"operatorAddress"`
+	BlockNumber     uint64           `bson:"blockNumber"`
+	Amount          string           `bson:"amount"`
+	TransactionHash string           `bson:"transactionHash"`
+	BlockTimestamp  time.Time        `bson:"blockTimestamp"`
+	Fee             float64          `bson:"fee"`
+	Fees            *TransactionFees `bson:"fees,omitempty"`
+	TransactionFrom string           `bson:"transactionFrom"`
+	ToContract      string           `bson:"toContract"`
+	BatchSize       int              `bson:"batchSize,omitempty"`
+	Source          string           `bson:"source,omitempty"`
+	LogIndex        *uint            `bson:"logIndex,omitempty"`
+
+	Cancelled             bool   `bson:"cancelled"`
+	CancelTransactionHash string `bson:"cancelTransactionHash,omitempty"`
+}
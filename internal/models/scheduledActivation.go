@@ -0,0 +1,9 @@
+package models
+
+type ScheduledActivation struct {
+	ValidatorPubkey  string `bson:"validatorPubkey" json:"validatorPubkey"`
+	OperatorAddress  string `bson:"operatorAddress" json:"operatorAddress"`
+	Amount           string `bson:"amount" json:"amount"`
+	QueueBlockNumber uint64 `bson:"queueBlockNumber" json:"queueBlockNumber"`
+	EligibleBlock    uint64 `bson:"eligibleBlock" json:"eligibleBlock"`
+}
@@ -0,0 +1,10 @@
+package models
+
+const (
+	// SourceReconciliation marks records written by reconciliation to bring the database in line with the BGT
+	// contract, rather than for a transaction sent by the engine
+	SourceReconciliation = "reconciliation"
+	// SourceIndexer marks records the indexer found in the BGT contract logs, such as transactions made outside
+	// this tool
+	SourceIndexer = "indexer"
+)
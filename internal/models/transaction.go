@@ -0,0 +1,46 @@
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+const (
+	// TransactionStateBuilt has a nonce and gas limit but was not signed yet
+	TransactionStateBuilt = "built"
+	// TransactionStateSigned was signed but not broadcast yet
+	TransactionStateSigned = "signed"
+	// TransactionStateBroadcast was sent to the RPC and is waiting to be mined
+	TransactionStateBroadcast = "broadcast"
+	TransactionStateMined     = "mined"
+	// TransactionStateFailed was never broadcast, or was mined but reverted
+	TransactionStateFailed = "failed"
+	// TransactionStateReplaced lost its nonce to another transaction of the same group
+	TransactionStateReplaced = "replaced"
+)
+
+// TransactionAction is one BGT call a transaction makes, kept so that its records can be written after a restart.
+type TransactionAction struct {
+	Method              string               `bson:"method"`
+	ValidatorPubkey     string               `bson:"validatorPubkey"`
+	Amount              string               `bson:"amount"`
+	DropBoostRequestIDs []primitive.ObjectID `bson:"dropBoostRequestIds,omitempty"`
+	// CancelsQueue is set on cancels of a validator's whole queue
+	CancelsQueue bool `bson:"cancelsQueue,omitempty"`
+}
+
+// Transaction is a journal entry for a transaction signed by the service.
+type Transaction struct {
+	ID primitive.ObjectID `bson:"_id"`
+	// GroupID is the ID of the first transaction signed for the intent, shared by the replacements of that transaction
+	GroupID             primitive.ObjectID  `bson:"groupId"`
+	OperatorAddress     string              `bson:"operatorAddress"`
+	Nonce               uint64              `bson:"nonce"`
+	Intent              []TransactionAction `bson:"intent"`
+	UnsignedTransaction string              `bson:"unsignedTransaction"`
+	SignedTransaction   string              `bson:"signedTransaction,omitempty"`
+	TransactionHash     string              `bson:"transactionHash,omitempty"`
+	State               string              `bson:"state"`
+	BlockNumber         uint64              `bson:"blockNumber,omitempty"`
+	BlockHash           string              `bson:"blockHash,omitempty"`
+	Error               string              `bson:"error,omitempty"`
+	// Recorded is set once the records of a mined transaction are written
+	Recorded bool `bson:"recorded"`
+}
@@ -0,0 +1,8 @@
+package models
+
+// TransactionFees are the fee caps a transaction was signed with and the price it paid per gas, in wei.
+type TransactionFees struct {
+	GasFeeCap         string `bson:"gasFeeCap"`
+	GasTipCap         string `bson:"gasTipCap"`
+	EffectiveGasPrice string `bson:"effectiveGasPrice"`
+}
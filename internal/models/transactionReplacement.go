@@ -0,0 +1,11 @@
+package models
+
+// TransactionReplacement links a transaction that was not mined in time to the replacements signed for its nonce.
+type TransactionReplacement struct {
+	OperatorAddress              string   `bson:"operatorAddress"`
+	Nonce                        uint64   `bson:"nonce"`
+	OriginalTransactionHash      string   `bson:"originalTransactionHash"`
+	ReplacementTransactionHashes []string `bson:"replacementTransactionHashes"`
+	// MinedTransactionHash is whichever of the transactions was mined
+	MinedTransactionHash string `bson:"minedTransactionHash,omitempty"`
+}
@@ -1,7 +1,9 @@
 package models
 
 type Validator struct {
-	Pubkey          string `bson:"pubkey,unique" json:"pubkey" validate:"required"`
-	OperatorAddress string `bson:"operatorAddress" json:"operatorAddress" validate:"required"`
-	BoostThreshold  string `bson:"boostThreshold" json:"boostThreshold" validate:"required"`
+	Pubkey               string `bson:"pubkey,unique" json:"pubkey" validate:"required"`
+	OperatorAddress      string `bson:"operatorAddress" json:"operatorAddress" validate:"required"`
+	BoostThreshold       string `bson:"boostThreshold" json:"boostThreshold" validate:"required"`
+	AllocationPercentage uint   `bson:"allocationPercentage" json:"allocationPercentage" validate:"max=100"`
+	MaxBoost             string `bson:"maxBoost" json:"maxBoost,omitempty"`
 }
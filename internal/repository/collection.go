@@ -15,7 +15,10 @@ type CollectionOperations interface {
 	FindOne(ctx context.Context, filter bson.M, opts ...*options.FindOneOptions) *mongo.SingleResult
 	FindMany(ctx context.Context, filter bson.M, opts *options.FindOptions, documents interface{}) error
 	UpdateOne(ctx context.Context, filter bson.M, update interface{}) error
+	UpdateMany(ctx context.Context, filter bson.M, update interface{}) error
+	UpsertOne(ctx context.Context, filter bson.M, update interface{}) error
 	DeleteOne(ctx context.Context, filter bson.M) error
+	DeleteMany(ctx context.Context, filter bson.M) error
 }
 
 type mongoCollection struct {
@@ -78,9 +81,47 @@ func (c *mongoCollection) UpdateOne(ctx context.Context, filter bson.M, update i
 	return nil
 }
 
+func (c *mongoCollection) UpdateMany(ctx context.Context, filter bson.M, update interface{}) error {
+	finalUpdate := bson.M{
+		"$set": update,
+		"$currentDate": bson.M{
+			"updated_at": true,
+		},
+	}
+
+	if _, err := c.coll.UpdateMany(ctx, filter, finalUpdate); err != nil {
+		return fmt.Errorf("failed to update documents: %v", err)
+	}
+	return nil
+}
+
+func (c *mongoCollection) UpsertOne(ctx context.Context, filter bson.M, update interface{}) error {
+	finalUpdate := bson.M{
+		"$set": update,
+		"$setOnInsert": bson.M{
+			"created_at": time.Now(),
+		},
+		"$currentDate": bson.M{
+			"updated_at": true,
+		},
+	}
+
+	if _, err := c.coll.UpdateOne(ctx, filter, finalUpdate, options.Update().SetUpsert(true)); err != nil {
+		return fmt.Errorf("failed to upsert document: %v", err)
+	}
+	return nil
+}
+
 func (c *mongoCollection) DeleteOne(ctx context.Context, filter bson.M) error {
 	if _, err := c.coll.DeleteOne(ctx, filter); err != nil {
 		return fmt.Errorf("failed to delete document: %v", err)
 	}
 	return nil
 }
+
+func (c *mongoCollection) DeleteMany(ctx context.Context, filter bson.M) error {
+	if _, err := c.coll.DeleteMany(ctx, filter); err != nil {
+		return fmt.Errorf("failed to delete documents: %v", err)
+	}
+	return nil
+}
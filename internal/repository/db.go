@@ -6,10 +6,14 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"math/big"
+	"reflect"
 	"strings"
 	"time"
 
+	"github.com/ethereum/go-ethereum/common"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
@@ -19,15 +23,98 @@ type DbRepository interface {
 	Disconnect() error
 	AddQueueBoost(ctx context.Context, boost models.QueueBoost) error
 	AddActivateBoost(ctx context.Context, boost models.ActivateBoost) error
-	GetInActiveBoosts(ctx context.Context) ([]models.QueueBoost, error)
+	AddQueueDropBoost(ctx context.Context, dropBoost models.QueueDropBoost) error
+	AddDropBoost(ctx context.Context, dropBoost models.DropBoost) error
+	AddDropBoostRequest(ctx context.Context, request models.DropBoostRequest) error
+	GetDropBoostRequests(ctx context.Context, pubkey string, status string) ([]models.DropBoostRequest, error)
+	MarkDropBoostRequestsQueued(ctx context.Context, ids []primitive.ObjectID, transactionHash string) error
+	MarkDropBoostRequestsDropped(ctx context.Context, ids []primitive.ObjectID, transactionHash string) error
+	GetUnrecordedDropBoostRequestIDs(ctx context.Context, pubkey string) ([]primitive.ObjectID, error)
+	AddCancelBoost(ctx context.Context, cancelBoost models.CancelBoost) error
+	AddCancelDropBoost(ctx context.Context, cancelDropBoost models.CancelDropBoost) error
+	MarkQueueBoostsCancelled(ctx context.Context, pubkey string, transactionHash string) error
+	MarkQueueBoostCancelledAmount(ctx context.Context, id primitive.ObjectID, cancelledAmount string, status string, transactionHash string) error
+	MarkQueueDropBoostsCancelled(ctx context.Context, pubkey string, transactionHash string) error
+	GetInActiveBoosts(ctx context.Context, pubkey string, blockNumber uint64) ([]models.QueueBoost, error)
 	DoesQueueBoostExist(ctx context.Context, pubkey string) (bool, error)
-	MarkBoostAsActivated(ctx context.Context, transactionHash string) error
+	MarkQueueBoostsSuperseded(ctx context.Context, pubkey string, transactionHash string, blockNumber uint64) error
+	MarkBoostsAsActivated(ctx context.Context, ids []primitive.ObjectID, transactionHash string, blockNumber uint64) error
 	GetValidators(ctx context.Context) ([]models.Validator, error)
 	GetValidator(ctx context.Context, pubkey string) (models.Validator, error)
 	DoesValidatorExist(ctx context.Context, pubkey string) (bool, error)
 	AddValidator(ctx context.Context, validator models.Validator) error
 	UpdateValidator(ctx context.Context, pubkey string, validator models.Validator) error
 	DeleteValidator(ctx context.Context, pubkey string) error
+	GetOperators(ctx context.Context) ([]models.Operator, error)
+	GetOperator(ctx context.Context, address string) (models.Operator, error)
+	DoesOperatorExist(ctx context.Context, address string) (bool, error)
+	AddOperator(ctx context.Context, operator models.Operator) error
+	UpdateOperator(ctx context.Context, address string, operator models.Operator) error
+	DeleteOperator(ctx context.Context, address string) error
+	GetOperatorHistory(ctx context.Context, address string) (OperatorHistory, error)
+	GetBoostTotals(ctx context.Context, pubkey string) (BoostTotals, error)
+	SaveIndexedLog(ctx context.Context, indexedLog IndexedLog) (bool, error)
+	GetIndexerCheckpoint(ctx context.Context) (uint64, error)
+	SaveIndexerCheckpoint(ctx context.Context, blockNumber uint64) error
+	GetInFlightNonces(ctx context.Context, address string) ([]models.InFlightNonce, error)
+	SaveInFlightNonce(ctx context.Context, nonce models.InFlightNonce) error
+	DeleteInFlightNonce(ctx context.Context, address string, nonce uint64) error
+	SaveTransactionReplacement(ctx context.Context, replacement models.TransactionReplacement) error
+	AddTransaction(ctx context.Context, transaction models.Transaction) error
+	MarkTransactionSigned(ctx context.Context, id primitive.ObjectID, signedTransaction string, transactionHash string) error
+	MarkTransactionState(ctx context.Context, id primitive.ObjectID, state string, errorMessage string) error
+	FinishTransaction(ctx context.Context, groupID primitive.ObjectID, transactionInfo TransactionInfo, state string, errorMessage string) error
+	MarkTransactionRecorded(ctx context.Context, transactionHash string) error
+	GetUnfinishedTransactions(ctx context.Context) ([]models.Transaction, error)
+	GetOperatorUnfinishedTransactions(ctx context.Context, address string) ([]models.Transaction, error)
+	GetTransactionGroup(ctx context.Context, groupID primitive.ObjectID) ([]models.Transaction, error)
+	GetMinedTransactions(ctx context.Context, fromBlock uint64) ([]models.Transaction, error)
+	MoveTransaction(ctx context.Context, transactionHash string, inclusion TransactionInclusion) error
+	ReopenTransaction(ctx context.Context, transactionHash string) error
+	GetScheduledActivations(ctx context.Context) ([]models.ScheduledActivation, error)
+	SaveScheduledActivation(ctx context.Context, activation models.ScheduledActivation) error
+	DeleteScheduledActivation(ctx context.Context, pubkey string) error
+}
+
+type OperatorHistory struct {
+	QueueBoosts      []models.QueueBoost      `json:"queueBoosts"`
+	ActivateBoosts   []models.ActivateBoost   `json:"activateBoosts"`
+	CancelBoosts     []models.CancelBoost     `json:"cancelBoosts"`
+	QueueDropBoosts  []models.QueueDropBoost  `json:"queueDropBoosts"`
+	DropBoosts       []models.DropBoost       `json:"dropBoosts"`
+	CancelDropBoosts []models.CancelDropBoost `json:"cancelDropBoosts"`
+}
+
+// IndexedLog is a boost event the indexer found in the BGT contract logs. Record is the models.QueueBoost,
+// models.ActivateBoost, models.CancelBoost, models.QueueDropBoost, models.DropBoost or models.CancelDropBoost to store.
+type IndexedLog struct {
+	TransactionHash string
+	LogIndex        uint
+	ValidatorPubkey string
+	BlockNumber     uint64
+	Amount          string
+	Record          interface{}
+}
+
+// indexedCollections maps each record type stored by the indexer to its collection.
+var indexedCollections = map[reflect.Type]string{
+	reflect.TypeOf(models.QueueBoost{}):      "queue_boosts",
+	reflect.TypeOf(models.ActivateBoost{}):   "activate_boosts",
+	reflect.TypeOf(models.CancelBoost{}):     "cancel_boosts",
+	reflect.TypeOf(models.QueueDropBoost{}):  "queue_drop_boosts",
+	reflect.TypeOf(models.DropBoost{}):       "drop_boosts",
+	reflect.TypeOf(models.CancelDropBoost{}): "cancel_drop_boosts",
+}
+
+// indexerCheckpointName identifies the boost event indexer's checkpoint in the indexer_checkpoints collection.
+const indexerCheckpointName = "bgt_boost_events"
+
+// BoostTotals are the amounts recorded for a validator, summed per collection.
+type BoostTotals struct {
+	Queued    *big.Int
+	Activated *big.Int
+	Cancelled *big.Int
+	Dropped   *big.Int
 }
 
 type mongoRepository struct {
@@ -64,6 +151,12 @@ func ConnectToDb(config *config.Config) (DbRepository, error) {
 	if err := repo.ensureIndexes(); err != nil {
 		return nil, fmt.Errorf("failed to ensure indexes: %v", err)
 	}
+	if err := repo.ensureOperators(); err != nil {
+		return nil, fmt.Errorf("failed to ensure operators: %v", err)
+	}
+	if err := repo.ensureQueueBoostStatuses(); err != nil {
+		return nil, fmt.Errorf("failed to ensure queue boost statuses: %v", err)
+	}
 
 	log.Println("✅ Connected to Database")
 	return repo, nil
@@ -84,10 +177,163 @@ func (r *mongoRepository) ensureIndexes() error {
 	if err := r.createIndexesIfNotExist(ctx, validatorsCollection, validatorsIndexes); err != nil {
 		return fmt.Errorf("failed to ensure indexes for validators collection: %v", err)
 	}
+
+	// Ensure indexes for the operators collection
+	operatorsCollection := r.client.Database(r.dbName).Collection("operators")
+	operatorsIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "address", Value: 1}},
+			Options: options.Index().SetName("address_index").SetUnique(true),
+		},
+	}
+	if err := r.createIndexesIfNotExist(ctx, operatorsCollection, operatorsIndexes); err != nil {
+		return fmt.Errorf("failed to ensure indexes for operators collection: %v", err)
+	}
+
+	// Ensure indexes for the scheduled_activations collection
+	scheduledActivationsCollection := r.client.Database(r.dbName).Collection("scheduled_activations")
+	scheduledActivationsIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "validatorPubkey", Value: 1}},
+			Options: options.Index().SetName("validator_pubkey_index").SetUnique(true),
+		},
+	}
+	if err := r.createIndexesIfNotExist(ctx, scheduledActivationsCollection, scheduledActivationsIndexes); err != nil {
+		return fmt.Errorf("failed to ensure indexes for scheduled_activations collection: %v", err)
+	}
+	// Ensure indexes for the nonces collection
+	noncesCollection := r.client.Database(r.dbName).Collection("nonces")
+	noncesIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "operatorAddress", Value: 1}, {Key: "nonce", Value: 1}},
+			Options: options.Index().SetName("operator_address_nonce_index").SetUnique(true),
+		},
+	}
+	if err := r.createIndexesIfNotExist(ctx, noncesCollection, noncesIndexes); err != nil {
+		return fmt.Errorf("failed to ensure indexes for nonces collection: %v", err)
+	}
+	// Ensure indexes for the transaction_replacements collection
+	replacementsCollection := r.client.Database(r.dbName).Collection("transaction_replacements")
+	replacementsIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "originalTransactionHash", Value: 1}},
+			Options: options.Index().SetName("original_transaction_hash_index").SetUnique(true),
+		},
+	}
+	if err := r.createIndexesIfNotExist(ctx, replacementsCollection, replacementsIndexes); err != nil {
+		return fmt.Errorf("failed to ensure indexes for transaction_replacements collection: %v", err)
+	}
+	// Ensure indexes for the transactions collection
+	transactionsCollection := r.client.Database(r.dbName).Collection("transactions")
+	transactionsIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "groupId", Value: 1}},
+			Options: options.Index().SetName("group_id_index"),
+		},
+		{
+			Keys:    bson.D{{Key: "state", Value: 1}},
+			Options: options.Index().SetName("state_index"),
+		},
+		{
+			Keys:    bson.D{{Key: "transactionHash", Value: 1}},
+			Options: options.Index().SetName("transaction_hash_index"),
+		},
+	}
+	if err := r.createIndexesIfNotExist(ctx, transactionsCollection, transactionsIndexes); err != nil {
+		return fmt.Errorf("failed to ensure indexes for transactions collection: %v", err)
+	}
+	// Ensure indexes for the records the indexer stores, which are unique per log
+	for _, name := range indexedCollections {
+		logIndexes := []mongo.IndexModel{
+			{
+				Keys: bson.D{{Key: "transactionHash", Value: 1}, {Key: "logIndex", Value: 1}},
+				Options: options.Index().SetName("transaction_hash_log_index").SetUnique(true).
+					SetPartialFilterExpression(bson.M{"logIndex": bson.M{"$exists": true}}),
+			},
+		}
+		if err := r.createIndexesIfNotExist(ctx, r.client.Database(r.dbName).Collection(name), logIndexes); err != nil {
+			return fmt.Errorf("failed to ensure indexes for %s collection: %v", name, err)
+		}
+	}
 	log.Println("✅ Indexes ensured successfully")
 	return nil
 }
 
+// ensureOperators creates an enabled operator for every validator whose operator address has no operator yet, so
+// that validators added before operators existed keep being boosted.
+func (r *mongoRepository) ensureOperators() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	validators, err := r.GetValidators(ctx)
+	if err != nil {
+		return err
+	}
+	for _, validator := range validators {
+		exists, err := r.DoesOperatorExist(ctx, validator.OperatorAddress)
+		if err != nil {
+			return err
+		}
+		if exists {
+			continue
+		}
+		if err := r.AddOperator(ctx, models.Operator{Address: validator.OperatorAddress, Enabled: true}); err != nil {
+			return err
+		}
+		log.Printf("Created operator: %s", common.HexToAddress(validator.OperatorAddress).Hex())
+	}
+	return nil
+}
+
+// ensureQueueBoostStatuses gives a status to queue records written before they had one. Records flagged as cancelled
+// are cancelled, records no later than the validator's last activation are activated and the rest are pending.
+func (r *mongoRepository) ensureQueueBoostStatuses() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	queueBoosts := r.Collection("queue_boosts")
+	missing := bson.M{"status": bson.M{"$exists": false}}
+	if err := queueBoosts.FindOne(ctx, missing).Err(); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil
+		}
+		return err
+	}
+
+	if err := queueBoosts.UpdateMany(ctx, bson.M{"status": bson.M{"$exists": false}, "cancelled": true}, bson.M{"status": models.QueueBoostStatusCancelled}); err != nil {
+		return err
+	}
+
+	cursor, err := r.client.Database(r.dbName).Collection("activate_boosts").Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$group", Value: bson.M{"_id": "$validatorPubkey", "blockNumber": bson.M{"$max": "$blockNumber"}}}},
+	})
+	if err != nil {
+		return err
+	}
+	var lastActivations []struct {
+		ValidatorPubkey string `bson:"_id"`
+		BlockNumber     uint64 `bson:"blockNumber"`
+	}
+	if err := cursor.All(ctx, &lastActivations); err != nil {
+		return err
+	}
+	if len(lastActivations) > 0 {
+		activated := make(bson.A, len(lastActivations))
+		for i, lastActivation := range lastActivations {
+			activated[i] = bson.M{"validatorPubkey": lastActivation.ValidatorPubkey, "blockNumber": bson.M{"$lte": lastActivation.BlockNumber}}
+		}
+		if err := queueBoosts.UpdateMany(ctx, bson.M{"status": bson.M{"$exists": false}, "$or": activated}, bson.M{"status": models.QueueBoostStatusActivated}); err != nil {
+			return err
+		}
+	}
+
+	if err := queueBoosts.UpdateMany(ctx, missing, bson.M{"status": models.QueueBoostStatusPending}); err != nil {
+		return err
+	}
+	log.Println("Set the status of queue boosts without one")
+	return nil
+}
+
 func (r *mongoRepository) createIndexesIfNotExist(ctx context.Context, collection *mongo.Collection, indexes []mongo.IndexModel) error {
 	cursor, err := collection.Indexes().List(ctx)
 	if err != nil {
@@ -158,9 +404,154 @@ func (r *mongoRepository) AddActivateBoost(ctx context.Context, boost models.Act
 	return r.Collection("activate_boosts").InsertOne(ctx, boost)
 }
 
-func (r *mongoRepository) GetInActiveBoosts(ctx context.Context) ([]models.QueueBoost, error) {
+func (r *mongoRepository) AddQueueDropBoost(ctx context.Context, dropBoost models.QueueDropBoost) error {
+	return r.Collection("queue_drop_boosts").InsertOne(ctx, dropBoost)
+}
+
+func (r *mongoRepository) AddDropBoost(ctx context.Context, dropBoost models.DropBoost) error {
+	return r.Collection("drop_boosts").InsertOne(ctx, dropBoost)
+}
+
+func (r *mongoRepository) AddDropBoostRequest(ctx context.Context, request models.DropBoostRequest) error {
+	return r.Collection("drop_boost_requests").InsertOne(ctx, request)
+}
+
+func (r *mongoRepository) GetDropBoostRequests(ctx context.Context, pubkey string, status string) ([]models.DropBoostRequest, error) {
+	filter := bson.M{"validatorPubkey": pubkey}
+	if status != "" {
+		filter["status"] = status
+	}
+	var requests []models.DropBoostRequest
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}})
+	if err := r.Collection("drop_boost_requests").FindMany(ctx, filter, opts, &requests); err != nil {
+		return nil, err
+	}
+	return requests, nil
+}
+
+func (r *mongoRepository) MarkDropBoostRequestsQueued(ctx context.Context, ids []primitive.ObjectID, transactionHash string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return r.Collection("drop_boost_requests").UpdateMany(ctx, bson.M{"_id": bson.M{"$in": ids}}, bson.M{
+		"status":               models.DropBoostRequestStatusQueued,
+		"queueTransactionHash": transactionHash,
+	})
+}
+
+func (r *mongoRepository) MarkDropBoostRequestsDropped(ctx context.Context, ids []primitive.ObjectID, transactionHash string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return r.Collection("drop_boost_requests").UpdateMany(ctx, bson.M{"_id": bson.M{"$in": ids}}, bson.M{
+		"status":              models.DropBoostRequestStatusDropped,
+		"dropTransactionHash": transactionHash,
+	})
+}
+
+// GetUnrecordedDropBoostRequestIDs returns the drop boost requests of the validator that a journaled queueDropBoost
+// settles while its records are not written yet, so that they are not queued a second time.
+func (r *mongoRepository) GetUnrecordedDropBoostRequestIDs(ctx context.Context, pubkey string) ([]primitive.ObjectID, error) {
+	var transactions []models.Transaction
+	filter := bson.M{
+		"intent":   bson.M{"$elemMatch": bson.M{"method": "queueDropBoost", "validatorPubkey": pubkey}},
+		"state":    bson.M{"$nin": []string{models.TransactionStateFailed, models.TransactionStateReplaced}},
+		"recorded": false,
+	}
+	if err := r.Collection("transactions").FindMany(ctx, filter, nil, &transactions); err != nil {
+		return nil, err
+	}
+	var ids []primitive.ObjectID
+	for _, transaction := range transactions {
+		for _, intended := range transaction.Intent {
+			if intended.ValidatorPubkey == pubkey {
+				ids = append(ids, intended.DropBoostRequestIDs...)
+			}
+		}
+	}
+	return ids, nil
+}
+
+func (r *mongoRepository) AddCancelBoost(ctx context.Context, cancelBoost models.CancelBoost) error {
+	return r.Collection("cancel_boosts").InsertOne(ctx, cancelBoost)
+}
+
+func (r *mongoRepository) AddCancelDropBoost(ctx context.Context, cancelDropBoost models.CancelDropBoost) error {
+	return r.Collection("cancel_drop_boosts").InsertOne(ctx, cancelDropBoost)
+}
+
+// MarkQueueBoostsCancelled cancels every queue record of the validator that was not yet settled by an activation.
+func (r *mongoRepository) MarkQueueBoostsCancelled(ctx context.Context, pubkey string, transactionHash string) error {
+	return r.Collection("queue_boosts").UpdateMany(ctx, inActiveQueueBoosts(pubkey), bson.M{
+		"status":                models.QueueBoostStatusCancelled,
+		"cancelTransactionHash": transactionHash,
+	})
+}
+
+// MarkQueueBoostCancelledAmount records the part of a queue record taken out by a partial cancel. The record is
+// cancelled once nothing of it is left in the queue.
+func (r *mongoRepository) MarkQueueBoostCancelledAmount(ctx context.Context, id primitive.ObjectID, cancelledAmount string, status string, transactionHash string) error {
+	return r.Collection("queue_boosts").UpdateOne(ctx, bson.M{"_id": id}, bson.M{
+		"status":                status,
+		"cancelledAmount":       cancelledAmount,
+		"cancelTransactionHash": transactionHash,
+	})
+}
+
+// MarkQueueDropBoostsCancelled cancels every queue drop record of the validator that was not yet settled by a drop,
+// along with the drop boost requests waiting on it.
+func (r *mongoRepository) MarkQueueDropBoostsCancelled(ctx context.Context, pubkey string, transactionHash string) error {
+	lastDropBlock, err := r.latestBlockNumber(ctx, "drop_boosts", pubkey)
+	if err != nil {
+		return err
+	}
+	err = r.Collection("queue_drop_boosts").UpdateMany(ctx, bson.M{
+		"validatorPubkey": pubkey,
+		"cancelled":       bson.M{"$ne": true},
+		"blockNumber":     bson.M{"$gt": lastDropBlock},
+	}, bson.M{
+		"cancelled":             true,
+		"cancelTransactionHash": transactionHash,
+	})
+	if err != nil {
+		return err
+	}
+	return r.Collection("drop_boost_requests").UpdateMany(ctx, bson.M{"validatorPubkey": pubkey, "status": models.DropBoostRequestStatusQueued}, bson.M{
+		"status":                models.DropBoostRequestStatusCancelled,
+		"cancelTransactionHash": transactionHash,
+	})
+}
+
+// latestBlockNumber returns the highest blockNumber recorded for the validator in the collection, or 0 if there is none.
+func (r *mongoRepository) latestBlockNumber(ctx context.Context, collection string, pubkey string) (uint64, error) {
+	var record struct {
+		BlockNumber uint64 `bson:"blockNumber"`
+	}
+	opts := options.FindOne().SetSort(bson.D{{Key: "blockNumber", Value: -1}})
+	if err := r.Collection(collection).FindOne(ctx, bson.M{"validatorPubkey": pubkey}, opts).Decode(&record); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return record.BlockNumber, nil
+}
+
+// inActiveQueueBoosts matches the validator's queue records that are still waiting in its boostedQueue.
+func inActiveQueueBoosts(pubkey string) bson.M {
+	return bson.M{
+		"validatorPubkey": pubkey,
+		"status":          bson.M{"$in": []string{models.QueueBoostStatusPending, models.QueueBoostStatusSuperseded}},
+	}
+}
+
+// GetInActiveBoosts returns the validator's queue records up to blockNumber that are still waiting for activation.
+func (r *mongoRepository) GetInActiveBoosts(ctx context.Context, pubkey string, blockNumber uint64) ([]models.QueueBoost, error) {
 	var queueBoosts []models.QueueBoost
-	if err := r.Collection("queue_boosts").FindMany(ctx, bson.M{"activated": false}, nil, &queueBoosts); err != nil {
+	filter := inActiveQueueBoosts(pubkey)
+	filter["blockNumber"] = bson.M{"$lte": blockNumber}
+	opts := options.Find().SetSort(bson.D{{Key: "blockNumber", Value: 1}})
+	if err := r.Collection("queue_boosts").FindMany(ctx, filter, opts, &queueBoosts); err != nil {
 		return nil, err
 	}
 	return queueBoosts, nil
@@ -168,7 +559,7 @@ func (r *mongoRepository) GetInActiveBoosts(ctx context.Context) ([]models.Queue
 
 func (r *mongoRepository) DoesQueueBoostExist(ctx context.Context, pubkey string) (bool, error) {
 	var queueBoost models.QueueBoost
-	if err := r.Collection("queue_boosts").FindOne(ctx, bson.M{"pubkey": pubkey, "activated": false}, nil).Decode(&queueBoost); err != nil {
+	if err := r.Collection("queue_boosts").FindOne(ctx, inActiveQueueBoosts(pubkey), nil).Decode(&queueBoost); err != nil {
 		if err == mongo.ErrNoDocuments {
 			return false, nil
 		}
@@ -177,8 +568,28 @@ func (r *mongoRepository) DoesQueueBoostExist(ctx context.Context, pubkey string
 	return true, nil
 }
 
-func (r *mongoRepository) MarkBoostAsActivated(ctx context.Context, transactionHash string) error {
-	return r.Collection("queue_boosts").UpdateOne(ctx, bson.M{"transactionHash": transactionHash}, bson.M{"activated": true})
+// MarkQueueBoostsSuperseded marks the validator's pending queue records from before blockNumber as superseded by a
+// new queueBoost, which restarted their activation delay.
+func (r *mongoRepository) MarkQueueBoostsSuperseded(ctx context.Context, pubkey string, transactionHash string, blockNumber uint64) error {
+	return r.Collection("queue_boosts").UpdateMany(ctx, bson.M{
+		"validatorPubkey": pubkey,
+		"status":          models.QueueBoostStatusPending,
+		"blockNumber":     bson.M{"$lt": blockNumber},
+	}, bson.M{
+		"status":                   models.QueueBoostStatusSuperseded,
+		"supersedeTransactionHash": transactionHash,
+	})
+}
+
+func (r *mongoRepository) MarkBoostsAsActivated(ctx context.Context, ids []primitive.ObjectID, transactionHash string, blockNumber uint64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return r.Collection("queue_boosts").UpdateMany(ctx, bson.M{"_id": bson.M{"$in": ids}}, bson.M{
+		"status":                  models.QueueBoostStatusActivated,
+		"activateTransactionHash": transactionHash,
+		"activateBlockNumber":     blockNumber,
+	})
 }
 
 func (r *mongoRepository) GetValidators(ctx context.Context) ([]models.Validator, error) {
@@ -221,3 +632,428 @@ func (r *mongoRepository) UpdateValidator(ctx context.Context, pubkey string, va
 func (r *mongoRepository) DeleteValidator(ctx context.Context, pubkey string) error {
 	return r.Collection("validators").DeleteOne(ctx, bson.M{"pubkey": pubkey})
 }
+
+func (r *mongoRepository) GetOperators(ctx context.Context) ([]models.Operator, error) {
+	var operators []models.Operator
+	if err := r.Collection("operators").FindMany(ctx, bson.M{}, nil, &operators); err != nil {
+		return nil, err
+	}
+	return operators, nil
+}
+
+// GetOperator looks the operator up by its checksummed address, so any casing of the address matches.
+func (r *mongoRepository) GetOperator(ctx context.Context, address string) (models.Operator, error) {
+	var operator models.Operator
+	if err := r.Collection("operators").FindOne(ctx, bson.M{"address": common.HexToAddress(address).Hex()}, nil).Decode(&operator); err != nil {
+		return models.Operator{}, err
+	}
+	return operator, nil
+}
+
+func (r *mongoRepository) DoesOperatorExist(ctx context.Context, address string) (bool, error) {
+	if _, err := r.GetOperator(ctx, address); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (r *mongoRepository) AddOperator(ctx context.Context, operator models.Operator) error {
+	operator.Address = common.HexToAddress(operator.Address).Hex()
+	return r.Collection("operators").InsertOne(ctx, operator)
+}
+
+func (r *mongoRepository) UpdateOperator(ctx context.Context, address string, operator models.Operator) error {
+	operator.Address = common.HexToAddress(operator.Address).Hex()
+	return r.Collection("operators").UpdateOne(ctx, bson.M{"address": common.HexToAddress(address).Hex()}, operator)
+}
+
+func (r *mongoRepository) DeleteOperator(ctx context.Context, address string) error {
+	return r.Collection("operators").DeleteOne(ctx, bson.M{"address": common.HexToAddress(address).Hex()})
+}
+
+func (r *mongoRepository) GetOperatorHistory(ctx context.Context, address string) (OperatorHistory, error) {
+	// Records keep the operator address as it was entered on the validator, so match it case-insensitively
+	filter := bson.M{"operatorAddress": bson.M{"$regex": "^" + common.HexToAddress(address).Hex() + "$", "$options": "i"}}
+	opts := options.Find().SetSort(bson.D{{Key: "blockNumber", Value: 1}})
+
+	var history OperatorHistory
+	collections := []struct {
+		name      string
+		documents interface{}
+	}{
+		{"queue_boosts", &history.QueueBoosts},
+		{"activate_boosts", &history.ActivateBoosts},
+		{"cancel_boosts", &history.CancelBoosts},
+		{"queue_drop_boosts", &history.QueueDropBoosts},
+		{"drop_boosts", &history.DropBoosts},
+		{"cancel_drop_boosts", &history.CancelDropBoosts},
+	}
+	for _, collection := range collections {
+		if err := r.Collection(collection.name).FindMany(ctx, filter, opts, collection.documents); err != nil {
+			return OperatorHistory{}, err
+		}
+	}
+	return history, nil
+}
+
+func (r *mongoRepository) GetScheduledActivations(ctx context.Context) ([]models.ScheduledActivation, error) {
+	var activations []models.ScheduledActivation
+	opts := options.Find().SetSort(bson.D{{Key: "eligibleBlock", Value: 1}})
+	if err := r.Collection("scheduled_activations").FindMany(ctx, bson.M{}, opts, &activations); err != nil {
+		return nil, err
+	}
+	return activations, nil
+}
+
+func (r *mongoRepository) SaveScheduledActivation(ctx context.Context, activation models.ScheduledActivation) error {
+	return r.Collection("scheduled_activations").UpsertOne(ctx, bson.M{"validatorPubkey": activation.ValidatorPubkey}, activation)
+}
+
+func (r *mongoRepository) DeleteScheduledActivation(ctx context.Context, pubkey string) error {
+	return r.Collection("scheduled_activations").DeleteOne(ctx, bson.M{"validatorPubkey": pubkey})
+}
+
+func (r *mongoRepository) GetBoostTotals(ctx context.Context, pubkey string) (BoostTotals, error) {
+	var totals BoostTotals
+	collections := []struct {
+		name  string
+		total **big.Int
+	}{
+		{"queue_boosts", &totals.Queued},
+		{"activate_boosts", &totals.Activated},
+		{"cancel_boosts", &totals.Cancelled},
+		{"drop_boosts", &totals.Dropped},
+	}
+	for _, collection := range collections {
+		total, err := r.sumAmounts(ctx, collection.name, pubkey)
+		if err != nil {
+			return BoostTotals{}, err
+		}
+		*collection.total = total
+	}
+	return totals, nil
+}
+
+// sumAmounts adds up the amount of every record of the validator in the collection. Amounts are stored as decimal
+// strings, so they are summed here rather than by Mongo.
+func (r *mongoRepository) sumAmounts(ctx context.Context, collection string, pubkey string) (*big.Int, error) {
+	var records []struct {
+		Amount string `bson:"amount"`
+	}
+	opts := options.Find().SetProjection(bson.M{"amount": 1})
+	if err := r.Collection(collection).FindMany(ctx, bson.M{"validatorPubkey": pubkey}, opts, &records); err != nil {
+		return nil, err
+	}
+	total := big.NewInt(0)
+	for _, record := range records {
+		amount, ok := new(big.Int).SetString(record.Amount, 10)
+		if !ok {
+			return nil, fmt.Errorf("invalid amount %q in %s", record.Amount, collection)
+		}
+		total.Add(total, amount)
+	}
+	return total, nil
+}
+
+// SaveIndexedLog stores the log's record unless the log was indexed before, and reports whether it was stored. A
+// record this service wrote for the same transaction and validator before it knew the log index is linked to the log
+// instead of being duplicated, and reconciliation corrections that stood in for the missing event are replaced by it.
+func (r *mongoRepository) SaveIndexedLog(ctx context.Context, indexedLog IndexedLog) (bool, error) {
+	name, ok := indexedCollections[reflect.TypeOf(indexedLog.Record)]
+	if !ok {
+		return false, fmt.Errorf("unsupported indexed record: %T", indexedLog.Record)
+	}
+	collection := r.Collection(name)
+
+	err := collection.FindOne(ctx, bson.M{"transactionHash": indexedLog.TransactionHash, "logIndex": indexedLog.LogIndex}).Err()
+	if err == nil {
+		return false, nil
+	}
+	if err != mongo.ErrNoDocuments {
+		return false, err
+	}
+
+	var unindexed struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	err = collection.FindOne(ctx, bson.M{
+		"transactionHash": indexedLog.TransactionHash,
+		"validatorPubkey": indexedLog.ValidatorPubkey,
+		"logIndex":        bson.M{"$exists": false},
+	}).Decode(&unindexed)
+	if err == nil {
+		return false, collection.UpdateOne(ctx, bson.M{"_id": unindexed.ID}, bson.M{"logIndex": indexedLog.LogIndex})
+	}
+	if err != mongo.ErrNoDocuments {
+		return false, err
+	}
+	if err := collection.InsertOne(ctx, indexedLog.Record); err != nil {
+		return false, err
+	}
+	return true, replaceCorrections(ctx, collection, indexedLog)
+}
+
+// replaceCorrections takes the amount of a newly indexed event off the validator's reconciliation records in the same
+// collection written at or after the event's block, oldest first, since those corrections were made while the event
+// was missing. Corrections that are used up are deleted.
+func replaceCorrections(ctx context.Context, collection CollectionOperations, indexedLog IndexedLog) error {
+	remaining, ok := new(big.Int).SetString(indexedLog.Amount, 10)
+	if !ok {
+		return fmt.Errorf("invalid amount %q", indexedLog.Amount)
+	}
+	var corrections []struct {
+		ID     primitive.ObjectID `bson:"_id"`
+		Amount string             `bson:"amount"`
+	}
+	filter := bson.M{
+		"validatorPubkey": indexedLog.ValidatorPubkey,
+		"source":          models.SourceReconciliation,
+		"blockNumber":     bson.M{"$gte": indexedLog.BlockNumber},
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "blockNumber", Value: 1}, {Key: "_id", Value: 1}})
+	if err := collection.FindMany(ctx, filter, opts, &corrections); err != nil {
+		return err
+	}
+	for _, correction := range corrections {
+		if remaining.Sign() == 0 {
+			break
+		}
+		amount, ok := new(big.Int).SetString(correction.Amount, 10)
+		if !ok {
+			return fmt.Errorf("invalid amount %q in correction %s", correction.Amount, correction.ID.Hex())
+		}
+		if amount.Cmp(remaining) <= 0 {
+			if err := collection.DeleteOne(ctx, bson.M{"_id": correction.ID}); err != nil {
+				return err
+			}
+			remaining.Sub(remaining, amount)
+			continue
+		}
+		if err := collection.UpdateOne(ctx, bson.M{"_id": correction.ID}, bson.M{"amount": amount.Sub(amount, remaining).String()}); err != nil {
+			return err
+		}
+		remaining.SetInt64(0)
+	}
+	return nil
+}
+
+// GetIndexerCheckpoint returns the last block the indexer finished, or 0 if it never ran.
+func (r *mongoRepository) GetIndexerCheckpoint(ctx context.Context) (uint64, error) {
+	var checkpoint struct {
+		BlockNumber uint64 `bson:"blockNumber"`
+	}
+	if err := r.Collection("indexer_checkpoints").FindOne(ctx, bson.M{"name": indexerCheckpointName}).Decode(&checkpoint); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return checkpoint.BlockNumber, nil
+}
+
+func (r *mongoRepository) SaveIndexerCheckpoint(ctx context.Context, blockNumber uint64) error {
+	return r.Collection("indexer_checkpoints").UpsertOne(ctx, bson.M{"name": indexerCheckpointName}, bson.M{"blockNumber": blockNumber})
+}
+
+func (r *mongoRepository) GetInFlightNonces(ctx context.Context, address string) ([]models.InFlightNonce, error) {
+	var nonces []models.InFlightNonce
+	opts := options.Find().SetSort(bson.D{{Key: "nonce", Value: 1}})
+	if err := r.Collection("nonces").FindMany(ctx, bson.M{"operatorAddress": common.HexToAddress(address).Hex()}, opts, &nonces); err != nil {
+		return nil, err
+	}
+	return nonces, nil
+}
+
+func (r *mongoRepository) SaveInFlightNonce(ctx context.Context, nonce models.InFlightNonce) error {
+	nonce.OperatorAddress = common.HexToAddress(nonce.OperatorAddress).Hex()
+	return r.Collection("nonces").UpsertOne(ctx, bson.M{"operatorAddress": nonce.OperatorAddress, "nonce": nonce.Nonce}, nonce)
+}
+
+func (r *mongoRepository) DeleteInFlightNonce(ctx context.Context, address string, nonce uint64) error {
+	return r.Collection("nonces").DeleteOne(ctx, bson.M{"operatorAddress": common.HexToAddress(address).Hex(), "nonce": nonce})
+}
+
+func (r *mongoRepository) SaveTransactionReplacement(ctx context.Context, replacement models.TransactionReplacement) error {
+	replacement.OperatorAddress = common.HexToAddress(replacement.OperatorAddress).Hex()
+	return r.Collection("transaction_replacements").UpsertOne(ctx, bson.M{"originalTransactionHash": replacement.OriginalTransactionHash}, replacement)
+}
+
+func (r *mongoRepository) AddTransaction(ctx context.Context, transaction models.Transaction) error {
+	transaction.OperatorAddress = common.HexToAddress(transaction.OperatorAddress).Hex()
+	return r.Collection("transactions").InsertOne(ctx, transaction)
+}
+
+func (r *mongoRepository) MarkTransactionSigned(ctx context.Context, id primitive.ObjectID, signedTransaction string, transactionHash string) error {
+	return r.Collection("transactions").UpdateOne(ctx, bson.M{"_id": id}, bson.M{
+		"state":             models.TransactionStateSigned,
+		"signedTransaction": signedTransaction,
+		"transactionHash":   transactionHash,
+	})
+}
+
+func (r *mongoRepository) MarkTransactionState(ctx context.Context, id primitive.ObjectID, state string, errorMessage string) error {
+	update := bson.M{"state": state}
+	if errorMessage != "" {
+		update["error"] = errorMessage
+	}
+	return r.Collection("transactions").UpdateOne(ctx, bson.M{"_id": id}, update)
+}
+
+// FinishTransaction gives the transaction of the group that was mined its final state and marks every other
+// transaction of the group still waiting as replaced. A reverted transaction has no records to write.
+func (r *mongoRepository) FinishTransaction(ctx context.Context, groupID primitive.ObjectID, transactionInfo TransactionInfo, state string, errorMessage string) error {
+	transactionHash := transactionInfo.TransactionHash
+	update := bson.M{
+		"state":       state,
+		"recorded":    state != models.TransactionStateMined,
+		"blockNumber": transactionInfo.BlockNumber,
+		"blockHash":   transactionInfo.BlockHash,
+	}
+	if errorMessage != "" {
+		update["error"] = errorMessage
+	}
+	if err := r.Collection("transactions").UpdateOne(ctx, bson.M{"groupId": groupID, "transactionHash": transactionHash}, update); err != nil {
+		return err
+	}
+	return r.Collection("transactions").UpdateMany(ctx, bson.M{
+		"groupId":         groupID,
+		"transactionHash": bson.M{"$ne": transactionHash},
+		"state":           bson.M{"$in": []string{models.TransactionStateBroadcast, models.TransactionStateReplaced}},
+	}, bson.M{"state": models.TransactionStateReplaced})
+}
+
+func (r *mongoRepository) MarkTransactionRecorded(ctx context.Context, transactionHash string) error {
+	return r.Collection("transactions").UpdateOne(ctx, bson.M{"transactionHash": transactionHash, "state": models.TransactionStateMined}, bson.M{"recorded": true})
+}
+
+// GetUnfinishedTransactions returns the transactions that were not known to be mined, and the mined ones whose records
+// were not written, along with the replaced transactions of their groups, oldest first.
+func (r *mongoRepository) GetUnfinishedTransactions(ctx context.Context) ([]models.Transaction, error) {
+	return r.getUnfinishedTransactions(ctx, bson.M{})
+}
+
+func (r *mongoRepository) GetOperatorUnfinishedTransactions(ctx context.Context, address string) ([]models.Transaction, error) {
+	return r.getUnfinishedTransactions(ctx, bson.M{"operatorAddress": address})
+}
+
+func (r *mongoRepository) getUnfinishedTransactions(ctx context.Context, filter bson.M) ([]models.Transaction, error) {
+	var unfinished []models.Transaction
+	filter["$or"] = []bson.M{
+		{"state": bson.M{"$in": []string{models.TransactionStateBuilt, models.TransactionStateSigned, models.TransactionStateBroadcast}}},
+		{"state": models.TransactionStateMined, "recorded": false},
+	}
+	if err := r.Collection("transactions").FindMany(ctx, filter, nil, &unfinished); err != nil {
+		return nil, err
+	}
+	if len(unfinished) == 0 {
+		return nil, nil
+	}
+
+	groupIDs := make([]primitive.ObjectID, 0, len(unfinished))
+	for _, transaction := range unfinished {
+		groupIDs = append(groupIDs, transaction.GroupID)
+	}
+	var transactions []models.Transaction
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}})
+	if err := r.Collection("transactions").FindMany(ctx, bson.M{"groupId": bson.M{"$in": groupIDs}}, opts, &transactions); err != nil {
+		return nil, err
+	}
+	return transactions, nil
+}
+
+// GetTransactionGroup returns the journal entries of a group, oldest first.
+func (r *mongoRepository) GetTransactionGroup(ctx context.Context, groupID primitive.ObjectID) ([]models.Transaction, error) {
+	var transactions []models.Transaction
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}})
+	if err := r.Collection("transactions").FindMany(ctx, bson.M{"groupId": groupID}, opts, &transactions); err != nil {
+		return nil, err
+	}
+	return transactions, nil
+}
+
+// GetMinedTransactions returns the recorded transactions mined from the given block on, in nonce order.
+func (r *mongoRepository) GetMinedTransactions(ctx context.Context, fromBlock uint64) ([]models.Transaction, error) {
+	var transactions []models.Transaction
+	filter := bson.M{
+		"state":       models.TransactionStateMined,
+		"recorded":    true,
+		"blockNumber": bson.M{"$gte": fromBlock},
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "operatorAddress", Value: 1}, {Key: "nonce", Value: 1}})
+	if err := r.Collection("transactions").FindMany(ctx, filter, opts, &transactions); err != nil {
+		return nil, err
+	}
+	return transactions, nil
+}
+
+// MoveTransaction updates the block of a transaction, and of the records written for it, after a reorg mined it again
+// in another block.
+func (r *mongoRepository) MoveTransaction(ctx context.Context, transactionHash string, inclusion TransactionInclusion) error {
+	filter := bson.M{"transactionHash": transactionHash}
+	err := r.Collection("transactions").UpdateMany(ctx, bson.M{"transactionHash": transactionHash, "state": models.TransactionStateMined}, bson.M{
+		"blockNumber": inclusion.BlockNumber,
+		"blockHash":   inclusion.BlockHash,
+	})
+	if err != nil {
+		return err
+	}
+	for _, name := range indexedCollections {
+		update := bson.M{
+			"blockNumber":    inclusion.BlockNumber,
+			"blockTimestamp": inclusion.BlockTimestamp,
+		}
+		if name == "queue_boosts" || name == "activate_boosts" {
+			update["blockHash"] = inclusion.BlockHash
+		}
+		if err := r.Collection(name).UpdateMany(ctx, filter, update); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recordUpdate is an update to the records of one collection that a filter matches.
+type recordUpdate struct {
+	collection string
+	filter     bson.M
+	update     bson.M
+}
+
+// reopenedRecords puts the queue records and drop boost requests a transaction settled back to what they were before.
+func reopenedRecords(transactionHash string) []recordUpdate {
+	return []recordUpdate{
+		{"queue_boosts", bson.M{"supersedeTransactionHash": transactionHash}, bson.M{"status": models.QueueBoostStatusPending, "supersedeTransactionHash": ""}},
+		{"queue_boosts", bson.M{"activateTransactionHash": transactionHash}, bson.M{"status": models.QueueBoostStatusPending, "activateTransactionHash": "", "activateBlockNumber": 0}},
+		{"queue_boosts", bson.M{"cancelTransactionHash": transactionHash}, bson.M{"status": models.QueueBoostStatusPending, "cancelTransactionHash": "", "cancelledAmount": ""}},
+		{"queue_drop_boosts", bson.M{"cancelTransactionHash": transactionHash}, bson.M{"cancelled": false, "cancelTransactionHash": ""}},
+		{"drop_boost_requests", bson.M{"queueTransactionHash": transactionHash}, bson.M{"status": models.DropBoostRequestStatusPending, "queueTransactionHash": ""}},
+		{"drop_boost_requests", bson.M{"dropTransactionHash": transactionHash}, bson.M{"status": models.DropBoostRequestStatusQueued, "dropTransactionHash": ""}},
+		{"drop_boost_requests", bson.M{"cancelTransactionHash": transactionHash}, bson.M{"status": models.DropBoostRequestStatusQueued, "cancelTransactionHash": ""}},
+	}
+}
+
+// ReopenTransaction undoes the records of a transaction that a reorg dropped from the chain, and puts it back in the
+// journal as broadcast so that it is waited for and recorded again.
+func (r *mongoRepository) ReopenTransaction(ctx context.Context, transactionHash string) error {
+	for _, name := range indexedCollections {
+		if err := r.Collection(name).DeleteMany(ctx, bson.M{"transactionHash": transactionHash}); err != nil {
+			return err
+		}
+	}
+
+	for _, reopened := range reopenedRecords(transactionHash) {
+		if err := r.Collection(reopened.collection).UpdateMany(ctx, reopened.filter, reopened.update); err != nil {
+			return err
+		}
+	}
+
+	return r.Collection("transactions").UpdateMany(ctx, bson.M{"transactionHash": transactionHash, "state": models.TransactionStateMined}, bson.M{
+		"state":       models.TransactionStateBroadcast,
+		"recorded":    false,
+		"blockNumber": 0,
+		"blockHash":   "",
+	})
+}
@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"bgt_boost/internal/models"
+	"maps"
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// applyUpdates runs the updates on a record of the collection the way UpdateMany would, for filters on single fields.
+func applyUpdates(collection string, record bson.M, updates []recordUpdate) bson.M {
+	updated := maps.Clone(record)
+	for _, u := range updates {
+		if u.collection != collection {
+			continue
+		}
+		matches := true
+		for field, value := range u.filter {
+			if updated[field] != value {
+				matches = false
+			}
+		}
+		if matches {
+			maps.Copy(updated, u.update)
+		}
+	}
+	return updated
+}
+
+func TestReopenedRecordsRestoreQueueBoosts(t *testing.T) {
+	const hash = "0xdropped"
+	updates := reopenedRecords(hash)
+
+	activated := bson.M{"status": models.QueueBoostStatusActivated, "activateTransactionHash": hash, "activateBlockNumber": 120}
+	want := bson.M{"status": models.QueueBoostStatusPending, "activateTransactionHash": "", "activateBlockNumber": 0}
+	if got := applyUpdates("queue_boosts", activated, updates); !reflect.DeepEqual(got, want) {
+		t.Errorf("activated queue boost: got %v, want %v", got, want)
+	}
+
+	superseded := bson.M{"status": models.QueueBoostStatusSuperseded, "supersedeTransactionHash": hash}
+	want = bson.M{"status": models.QueueBoostStatusPending, "supersedeTransactionHash": ""}
+	if got := applyUpdates("queue_boosts", superseded, updates); !reflect.DeepEqual(got, want) {
+		t.Errorf("superseded queue boost: got %v, want %v", got, want)
+	}
+
+	cancelled := bson.M{"status": models.QueueBoostStatusCancelled, "cancelTransactionHash": hash, "cancelledAmount": "40"}
+	want = bson.M{"status": models.QueueBoostStatusPending, "cancelTransactionHash": "", "cancelledAmount": ""}
+	if got := applyUpdates("queue_boosts", cancelled, updates); !reflect.DeepEqual(got, want) {
+		t.Errorf("cancelled queue boost: got %v, want %v", got, want)
+	}
+
+	// Records settled by another transaction stay as they are
+	other := bson.M{"status": models.QueueBoostStatusActivated, "activateTransactionHash": "0xother", "activateBlockNumber": 90}
+	if got := applyUpdates("queue_boosts", other, updates); !reflect.DeepEqual(got, other) {
+		t.Errorf("queue boost of another transaction: got %v, want it unchanged", got)
+	}
+}
+
+func TestReopenedRecordsRestoreDropRequests(t *testing.T) {
+	const hash = "0xdropped"
+	updates := reopenedRecords(hash)
+
+	for _, record := range []struct {
+		collection string
+		before     bson.M
+		after      bson.M
+	}{
+		{
+			"drop_boost_requests",
+			bson.M{"status": models.DropBoostRequestStatusQueued, "queueTransactionHash": hash},
+			bson.M{"status": models.DropBoostRequestStatusPending, "queueTransactionHash": ""},
+		},
+		{
+			"drop_boost_requests",
+			bson.M{"status": models.DropBoostRequestStatusDropped, "queueTransactionHash": "0xqueued", "dropTransactionHash": hash},
+			bson.M{"status": models.DropBoostRequestStatusQueued, "queueTransactionHash": "0xqueued", "dropTransactionHash": ""},
+		},
+		{
+			"drop_boost_requests",
+			bson.M{"status": models.DropBoostRequestStatusCancelled, "cancelTransactionHash": hash},
+			bson.M{"status": models.DropBoostRequestStatusQueued, "cancelTransactionHash": ""},
+		},
+		{
+			"queue_drop_boosts",
+			bson.M{"cancelled": true, "cancelTransactionHash": hash},
+			bson.M{"cancelled": false, "cancelTransactionHash": ""},
+		},
+	} {
+		if got := applyUpdates(record.collection, record.before, updates); !reflect.DeepEqual(got, record.after) {
+			t.Errorf("%s %v: got %v, want %v", record.collection, record.before, got, record.after)
+		}
+	}
+}
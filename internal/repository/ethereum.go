@@ -4,45 +4,84 @@ import (
 	"bgt_boost/internal/config"
 	"bgt_boost/internal/utils"
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"math/big"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/cenkalti/backoff/v5"
 	"github.com/ethereum/go-ethereum"
-	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/ethclient"
 )
 
+// ErrTransactionReverted is returned for transactions that were mined but reverted, which still used up their nonce.
+var ErrTransactionReverted = errors.New("transaction reverted")
+
+// ErrInclusionTimeout is returned when a transaction was not mined within the inclusion timeout.
+var ErrInclusionTimeout = errors.New("transaction not mined within the inclusion timeout")
+
+// ErrPreflightRevert is returned when a transaction reverts in eth_call or gas estimation, so it would revert if it
+// were sent.
+var ErrPreflightRevert = errors.New("pre-flight revert")
+
+// ErrGasLimitExceeded is returned when a call is estimated above the gas limit of its method.
+var ErrGasLimitExceeded = errors.New("gas limit exceeded")
+
+// receiptPollInterval is how often WaitForTransaction checks for receipts.
+const receiptPollInterval = time.Second
+
 type EthRepository interface {
 	GetLatestBlock(ctx context.Context) (uint64, error)
 	GetBlockTimestamp(ctx context.Context, blockNumber uint64) (time.Time, error)
+	SubscribeNewHeads(ctx context.Context, heads chan<- *types.Header) (ethereum.Subscription, error)
+	GetTransferRecipients(ctx context.Context, fromBlock uint64, toBlock uint64, recipients []common.Address) ([]common.Address, error)
+	GetBoostLogs(ctx context.Context, fromBlock uint64, toBlock uint64, operatorAddresses []common.Address, pubkeyTopics []common.Hash) ([]types.Log, error)
 	GetActivateBoostDelay(ctx context.Context) (uint64, error)
 	GetUnboostedBalance(ctx context.Context, operatorAddress common.Address) (*big.Int, error)
+	GetBalance(ctx context.Context, operatorAddress common.Address) (*big.Int, error)
+	GetBoosts(ctx context.Context, operatorAddress common.Address) (*big.Int, error)
+	GetQueuedBoost(ctx context.Context, operatorAddress common.Address) (*big.Int, error)
 	GetBoostedQueue(ctx context.Context, operatorAddress common.Address, validatorPubkey string) (BoostedQueue, error)
-	CreateTransaction(ctx context.Context, fromAddress common.Address, toAddress common.Address, data []byte) (*types.Transaction, error)
-	SendTransaction(ctx context.Context, signedTx *types.Transaction) (TransactionInfo, error)
+	GetBoosted(ctx context.Context, operatorAddress common.Address, validatorPubkey string) (*big.Int, error)
+	GetDropBoostDelay(ctx context.Context) (uint64, error)
+	GetDropBoostQueue(ctx context.Context, operatorAddress common.Address, validatorPubkey string) (BoostedQueue, error)
+	SuggestGasPrice(ctx context.Context) (*big.Int, error)
+	SuggestGasTipCap(ctx context.Context) (*big.Int, error)
+	GetBaseFee(ctx context.Context) (*big.Int, error)
+	GetFeeHistoryTips(ctx context.Context, blocks int, percentile float64) ([]*big.Int, error)
+	SimulateTransaction(ctx context.Context, fromAddress common.Address, toAddress common.Address, data []byte) error
+	EstimateGas(ctx context.Context, fromAddress common.Address, toAddress common.Address, data []byte) (uint64, error)
+	GetNonces(ctx context.Context, address common.Address) (uint64, uint64, error)
+	IsTransactionKnown(ctx context.Context, transactionHash common.Hash) (bool, error)
+	CreateTransaction(ctx context.Context, fromAddress common.Address, toAddress common.Address, data []byte, nonce uint64, fees TransactionFees) (*types.Transaction, error)
+	BroadcastTransaction(ctx context.Context, signedTx *types.Transaction) error
+	WaitForTransaction(ctx context.Context, transactionHashes []common.Hash, timeoutBlocks uint64) (TransactionInfo, error)
+	GetTransactionInclusion(ctx context.Context, transactionHash common.Hash) (*TransactionInclusion, error)
+	IsTransactionDropped(ctx context.Context, transactionHash common.Hash, blockNumber uint64) (bool, error)
 }
 
 type ethRepository struct {
-	client *ethclient.Client
+	rpc    *RPCPool
 	config *config.Config
 }
 
-func NewEthRepository(client *ethclient.Client, config *config.Config) EthRepository {
+func NewEthRepository(rpc *RPCPool, config *config.Config) EthRepository {
 	return &ethRepository{
-		client: client,
+		rpc:    rpc,
 		config: config,
 	}
 }
 
 func (r *ethRepository) GetLatestBlock(ctx context.Context) (uint64, error) {
 	operation := func() (uint64, error) {
-		block, err := r.client.BlockNumber(ctx)
+		client := r.rpc.client()
+		block, err := client.BlockNumber(ctx)
 		if err != nil {
+			r.rpc.fail(client, err)
 			return 0, fmt.Errorf("failed to fetch block number: %w", err)
 		}
 		return block, nil
@@ -52,8 +91,10 @@ func (r *ethRepository) GetLatestBlock(ctx context.Context) (uint64, error) {
 
 func (r *ethRepository) GetBlockTimestamp(ctx context.Context, blockNumber uint64) (time.Time, error) {
 	operation := func() (time.Time, error) {
-		block, err := r.client.BlockByNumber(ctx, big.NewInt(int64(blockNumber)))
+		client := r.rpc.client()
+		block, err := client.BlockByNumber(ctx, big.NewInt(int64(blockNumber)))
 		if err != nil {
+			r.rpc.fail(client, err)
 			return time.Time{}, fmt.Errorf("failed to fetch block: %w", err)
 		}
 		return time.Unix(int64(block.Time()), 0), nil
@@ -61,9 +102,101 @@ func (r *ethRepository) GetBlockTimestamp(ctx context.Context, blockNumber uint6
 	return backoff.Retry(ctx, operation, backoff.WithBackOff(backoff.NewExponentialBackOff()))
 }
 
+func (r *ethRepository) SubscribeNewHeads(ctx context.Context, heads chan<- *types.Header) (ethereum.Subscription, error) {
+	return r.rpc.client().SubscribeNewHead(ctx, heads)
+}
+
+// GetTransferRecipients returns which of the recipients received BGT between fromBlock and toBlock, inclusive.
+func (r *ethRepository) GetTransferRecipients(ctx context.Context, fromBlock uint64, toBlock uint64, recipients []common.Address) ([]common.Address, error) {
+	if len(recipients) == 0 {
+		return nil, nil
+	}
+	recipientTopics := make([]common.Hash, len(recipients))
+	for i, recipient := range recipients {
+		recipientTopics[i] = common.BytesToHash(recipient.Bytes())
+	}
+	query := ethereum.FilterQuery{
+		FromBlock: new(big.Int).SetUint64(fromBlock),
+		ToBlock:   new(big.Int).SetUint64(toBlock),
+		Addresses: []common.Address{r.config.BGTContract.Address},
+		Topics:    [][]common.Hash{{r.config.BGTContract.ABI.Events["Transfer"].ID}, nil, recipientTopics},
+	}
+	logs, err := r.filterLogs(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[common.Address]bool)
+	var received []common.Address
+	for _, l := range logs {
+		recipient := common.BytesToAddress(l.Topics[2].Bytes())
+		if !seen[recipient] {
+			seen[recipient] = true
+			received = append(received, recipient)
+		}
+	}
+	return received, nil
+}
+
+// GetBoostLogs returns the BGT boost events of the operators for the validators whose pubkey hashes are in
+// pubkeyTopics, between fromBlock and toBlock, inclusive, ordered as they happened.
+func (r *ethRepository) GetBoostLogs(ctx context.Context, fromBlock uint64, toBlock uint64, operatorAddresses []common.Address, pubkeyTopics []common.Hash) ([]types.Log, error) {
+	if len(operatorAddresses) == 0 || len(pubkeyTopics) == 0 {
+		return nil, nil
+	}
+	operatorTopics := make([]common.Hash, len(operatorAddresses))
+	for i, operatorAddress := range operatorAddresses {
+		operatorTopics[i] = common.BytesToHash(operatorAddress.Bytes())
+	}
+	events := r.config.BGTContract.ABI.Events
+	// The operator is the first indexed argument of most boost events, but activateBoost and dropBoost can be called
+	// by anyone on the operator's behalf and index the sender first
+	userFirst := []common.Hash{events["QueueBoost"].ID, events["CancelBoost"].ID, events["QueueDropBoost"].ID, events["CancelDropBoost"].ID}
+	senderFirst := []common.Hash{events["ActivateBoost"].ID, events["DropBoost"].ID}
+
+	var logs []types.Log
+	for _, topics := range [][][]common.Hash{{userFirst, operatorTopics, pubkeyTopics}, {senderFirst, nil, operatorTopics, pubkeyTopics}} {
+		found, err := r.filterLogs(ctx, ethereum.FilterQuery{
+			FromBlock: new(big.Int).SetUint64(fromBlock),
+			ToBlock:   new(big.Int).SetUint64(toBlock),
+			Addresses: []common.Address{r.config.BGTContract.Address},
+			Topics:    topics,
+		})
+		if err != nil {
+			return nil, err
+		}
+		logs = append(logs, found...)
+	}
+	sort.Slice(logs, func(i, j int) bool {
+		if logs[i].BlockNumber != logs[j].BlockNumber {
+			return logs[i].BlockNumber < logs[j].BlockNumber
+		}
+		return logs[i].Index < logs[j].Index
+	})
+	return logs, nil
+}
+
+func (r *ethRepository) filterLogs(ctx context.Context, query ethereum.FilterQuery) ([]types.Log, error) {
+	operation := func() ([]types.Log, error) {
+		client := r.rpc.client()
+		logs, err := client.FilterLogs(ctx, query)
+		if err != nil {
+			r.rpc.fail(client, err)
+			return nil, fmt.Errorf("failed to filter logs: %w", err)
+		}
+		return logs, nil
+	}
+	return backoff.Retry(ctx, operation, backoff.WithBackOff(backoff.NewExponentialBackOff()))
+}
+
 func (r *ethRepository) callContract(ctx context.Context, callMsg ethereum.CallMsg) ([]byte, error) {
 	operation := func() ([]byte, error) {
-		return r.client.CallContract(ctx, callMsg, nil)
+		client := r.rpc.client()
+		result, err := client.CallContract(ctx, callMsg, nil)
+		if err != nil {
+			r.rpc.fail(client, err)
+		}
+		return result, err
 	}
 	return backoff.Retry(ctx, operation, backoff.WithBackOff(backoff.NewExponentialBackOff()))
 }
@@ -100,13 +233,85 @@ func (r *ethRepository) GetUnboostedBalance(ctx context.Context, operatorAddress
 	return balance, nil
 }
 
+func (r *ethRepository) GetBalance(ctx context.Context, operatorAddress common.Address) (*big.Int, error) {
+	return r.getAccountAmount(ctx, "balanceOf", operatorAddress)
+}
+
+func (r *ethRepository) GetBoosts(ctx context.Context, operatorAddress common.Address) (*big.Int, error) {
+	return r.getAccountAmount(ctx, "boosts", operatorAddress)
+}
+
+func (r *ethRepository) GetQueuedBoost(ctx context.Context, operatorAddress common.Address) (*big.Int, error) {
+	return r.getAccountAmount(ctx, "queuedBoost", operatorAddress)
+}
+
+// getAccountAmount reads one of the BGT views that take an account and return a single amount.
+func (r *ethRepository) getAccountAmount(ctx context.Context, method string, operatorAddress common.Address) (*big.Int, error) {
+	data, err := r.config.BGTContract.ABI.Pack(method, operatorAddress)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack data: %w", err)
+	}
+	callMsg := ethereum.CallMsg{
+		To:   &r.config.BGTContract.Address,
+		Data: data,
+	}
+
+	response, err := r.callContract(ctx, callMsg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call contract: %w", err)
+	}
+	amount := new(big.Int).SetBytes(response)
+	return amount, nil
+}
+
 type BoostedQueue struct {
 	Balance     *big.Int
 	BlockNumber uint64
 }
 
 func (r *ethRepository) GetBoostedQueue(ctx context.Context, operatorAddress common.Address, pubkey string) (BoostedQueue, error) {
-	data, err := r.config.BGTContract.ABI.Pack("boostedQueue", operatorAddress, common.FromHex(pubkey))
+	return r.getQueue(ctx, "boostedQueue", operatorAddress, pubkey)
+}
+
+func (r *ethRepository) GetBoosted(ctx context.Context, operatorAddress common.Address, pubkey string) (*big.Int, error) {
+	data, err := r.config.BGTContract.ABI.Pack("boosted", operatorAddress, common.FromHex(pubkey))
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack data: %w", err)
+	}
+	callMsg := ethereum.CallMsg{
+		To:   &r.config.BGTContract.Address,
+		Data: data,
+	}
+
+	response, err := r.callContract(ctx, callMsg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call contract: %w", err)
+	}
+	boosted := new(big.Int).SetBytes(response)
+	return boosted, nil
+}
+
+func (r *ethRepository) GetDropBoostDelay(ctx context.Context) (uint64, error) {
+	callMsg := ethereum.CallMsg{
+		To:   &r.config.BGTContract.Address,
+		Data: r.config.BGTContract.ABI.Methods["dropBoostDelay"].ID,
+	}
+
+	response, err := r.callContract(ctx, callMsg)
+	if err != nil {
+		return 0, fmt.Errorf("failed to call contract: %w", err)
+	}
+	delay := new(big.Int).SetBytes(response)
+	return delay.Uint64(), nil
+}
+
+func (r *ethRepository) GetDropBoostQueue(ctx context.Context, operatorAddress common.Address, pubkey string) (BoostedQueue, error) {
+	return r.getQueue(ctx, "dropBoostQueue", operatorAddress, pubkey)
+}
+
+// getQueue reads one of the (blockNumberLast, balance) queue mappings of the BGT contract.
+func (r *ethRepository) getQueue(ctx context.Context, method string, operatorAddress common.Address, pubkey string) (BoostedQueue, error) {
+	data, err := r.config.BGTContract.ABI.Pack(method, operatorAddress, common.FromHex(pubkey))
 	if err != nil {
 		return BoostedQueue{}, fmt.Errorf("failed to pack data: %w", err)
 	}
@@ -119,7 +324,7 @@ func (r *ethRepository) GetBoostedQueue(ctx context.Context, operatorAddress com
 	if err != nil {
 		return BoostedQueue{}, fmt.Errorf("failed to call contract: %w", err)
 	}
-	result, err := r.config.BGTContract.ABI.Methods["boostedQueue"].Outputs.UnpackValues(response)
+	result, err := r.config.BGTContract.ABI.Methods[method].Outputs.UnpackValues(response)
 	if err != nil {
 		return BoostedQueue{}, fmt.Errorf("failed to decode response: %w", err)
 	}
@@ -129,81 +334,437 @@ func (r *ethRepository) GetBoostedQueue(ctx context.Context, operatorAddress com
 	}, nil
 }
 
-type TransactionInfo struct {
-	TransactionHash string
-	TransactionFee  float64
-	BlockNumber     uint64
-	BlockTimestamp  time.Time
+func (r *ethRepository) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
+	operation := func() (*big.Int, error) {
+		client := r.rpc.client()
+		gasPrice, err := client.SuggestGasPrice(ctx)
+		if err != nil {
+			r.rpc.fail(client, err)
+			return nil, fmt.Errorf("failed to get gas price: %w", err)
+		}
+		return gasPrice, nil
+	}
+	return backoff.Retry(ctx, operation, backoff.WithBackOff(backoff.NewExponentialBackOff()))
 }
 
-func (r *ethRepository) CreateTransaction(ctx context.Context, fromAddress common.Address, toAddress common.Address, data []byte) (*types.Transaction, error) {
-	operation := func() (*types.Transaction, error) {
-		nonce, err := r.client.PendingNonceAt(ctx, fromAddress)
+func (r *ethRepository) SuggestGasTipCap(ctx context.Context) (*big.Int, error) {
+	operation := func() (*big.Int, error) {
+		client := r.rpc.client()
+		tipCap, err := client.SuggestGasTipCap(ctx)
 		if err != nil {
-			log.Println("failed to get nonce: ", err.Error())
-			return nil, fmt.Errorf("failed to get nonce: %w", err)
+			r.rpc.fail(client, err)
+			return nil, fmt.Errorf("failed to get gas tip cap: %w", err)
 		}
-		gasPrice, err := r.client.SuggestGasPrice(ctx)
+		return tipCap, nil
+	}
+	return backoff.Retry(ctx, operation, backoff.WithBackOff(backoff.NewExponentialBackOff()))
+}
+
+func (r *ethRepository) GetBaseFee(ctx context.Context) (*big.Int, error) {
+	operation := func() (*big.Int, error) {
+		client := r.rpc.client()
+		header, err := client.HeaderByNumber(ctx, nil)
 		if err != nil {
-			log.Println("failed to get gas price: ", err.Error())
-			return nil, fmt.Errorf("failed to get gas price: %w", err)
+			r.rpc.fail(client, err)
+			return nil, fmt.Errorf("failed to fetch latest header: %w", err)
 		}
-		tipCap, err := r.client.SuggestGasTipCap(ctx)
+		if header.BaseFee == nil {
+			return nil, backoff.Permanent(fmt.Errorf("latest header has no base fee"))
+		}
+		return header.BaseFee, nil
+	}
+	return backoff.Retry(ctx, operation, backoff.WithBackOff(backoff.NewExponentialBackOff()))
+}
+
+// GetFeeHistoryTips returns the priority fee paid at the given percentile in each of the latest blocks.
+func (r *ethRepository) GetFeeHistoryTips(ctx context.Context, blocks int, percentile float64) ([]*big.Int, error) {
+	operation := func() ([]*big.Int, error) {
+		client := r.rpc.client()
+		history, err := client.FeeHistory(ctx, uint64(blocks), nil, []float64{percentile})
 		if err != nil {
-			log.Println("failed to get gas tip cap: ", err.Error())
-			return nil, fmt.Errorf("failed to get gas tip cap: %w", err)
+			r.rpc.fail(client, err)
+			return nil, fmt.Errorf("failed to get fee history: %w", err)
 		}
-		tx := types.NewTx(&types.DynamicFeeTx{
-			Nonce:     nonce,
-			GasTipCap: tipCap,
-			GasFeeCap: gasPrice,
-			Gas:       uint64(r.config.GasLimit),
-			To:        &toAddress,
-			Value:     big.NewInt(0),
-			Data:      data,
-		})
-		return tx, nil
+		tips := make([]*big.Int, 0, len(history.Reward))
+		for _, reward := range history.Reward {
+			if len(reward) > 0 {
+				tips = append(tips, reward[0])
+			}
+		}
+		return tips, nil
 	}
 	return backoff.Retry(ctx, operation, backoff.WithBackOff(backoff.NewExponentialBackOff()))
 }
 
-func (r *ethRepository) SendTransaction(ctx context.Context, signedTx *types.Transaction) (TransactionInfo, error) {
-	operation := func() (*types.Receipt, error) {
-		err := r.client.SendTransaction(ctx, signedTx)
+// SimulateTransaction runs the call with eth_call against the latest block. A revert fails with ErrPreflightRevert,
+// wrapping the decoded revert, and is not retried since it would fail the same way every time; RPC errors are retried.
+// A call BGT turns down by returning false fails the same way, see checkResult.
+func (r *ethRepository) SimulateTransaction(ctx context.Context, fromAddress common.Address, toAddress common.Address, data []byte) error {
+	callMsg := ethereum.CallMsg{
+		From: fromAddress,
+		To:   &toAddress,
+		Data: data,
+	}
+	operation := func() (struct{}, error) {
+		client := r.rpc.client()
+		result, err := client.CallContract(ctx, callMsg, nil)
+		if err != nil && isRevert(err) {
+			return struct{}{}, backoff.Permanent(fmt.Errorf("%w: %w", ErrPreflightRevert, r.decodeRevert(err)))
+		}
 		if err != nil {
-			log.Println("failed to send transaction: ", err.Error())
-			return nil, fmt.Errorf("failed to send transaction: %w", err)
+			r.rpc.fail(client, err)
+			return struct{}{}, fmt.Errorf("simulation failed: %w", err)
+		}
+		if err := r.checkResult(data, result); err != nil {
+			return struct{}{}, backoff.Permanent(err)
 		}
-		log.Println("Transaction sent: ", signedTx.Hash().Hex())
+		return struct{}{}, nil
+	}
+	_, err := backoff.Retry(ctx, operation, backoff.WithBackOff(backoff.NewExponentialBackOff()))
+	return err
+}
 
-		receipt, err := bind.WaitMinedHash(ctx, r.client, signedTx.Hash())
+// EstimateGas estimates the gas of the call. Like SimulateTransaction, a revert fails with ErrPreflightRevert and RPC
+// errors are retried.
+func (r *ethRepository) EstimateGas(ctx context.Context, fromAddress common.Address, toAddress common.Address, data []byte) (uint64, error) {
+	callMsg := ethereum.CallMsg{
+		From: fromAddress,
+		To:   &toAddress,
+		Data: data,
+	}
+	operation := func() (uint64, error) {
+		client := r.rpc.client()
+		gas, err := client.EstimateGas(ctx, callMsg)
+		if err != nil && isRevert(err) {
+			return 0, backoff.Permanent(fmt.Errorf("%w: %w", ErrPreflightRevert, r.decodeRevert(err)))
+		}
 		if err != nil {
-			log.Println("failed to wait for transaction to be mined: ", err.Error())
-			return nil, fmt.Errorf("failed to wait for transaction to be mined: %w", err)
+			r.rpc.fail(client, err)
+			return 0, fmt.Errorf("failed to estimate gas: %w", err)
 		}
-		return receipt, nil
+		return gas, nil
 	}
-	receipt, err := backoff.Retry(ctx, operation, backoff.WithBackOff(backoff.NewExponentialBackOff()))
+	return backoff.Retry(ctx, operation, backoff.WithBackOff(backoff.NewExponentialBackOff()))
+}
+
+// gasLimit estimates the gas of the call plus GAS_BUFFER_PERCENT, capped at the limit for the called method from
+// METHOD_GAS_LIMITS, or GAS_LIMIT for methods without one. A call estimated above its limit is not sent at all.
+func (r *ethRepository) gasLimit(ctx context.Context, fromAddress common.Address, toAddress common.Address, data []byte) (uint64, error) {
+	method := "unknown method"
+	if len(data) >= 4 {
+		if m, err := r.config.BGTContract.ABI.MethodById(data[:4]); err == nil {
+			method = m.Name
+		}
+	}
+	maxGas := uint64(r.config.GasLimit)
+	if limit, ok := r.config.MethodGasLimits[method]; ok {
+		maxGas = limit
+	}
+
+	gas, err := r.EstimateGas(ctx, fromAddress, toAddress, data)
 	if err != nil {
-		return TransactionInfo{}, fmt.Errorf("failed to wait for transaction to be mined: %w", err)
+		return 0, fmt.Errorf("%s: %w", method, err)
 	}
-	if receipt.Status == 0 {
-		return TransactionInfo{}, fmt.Errorf("transaction failed")
+	if gas > maxGas {
+		return 0, fmt.Errorf("%w: %s is estimated at %d gas, above its %d gas limit", ErrGasLimitExceeded, method, gas, maxGas)
 	}
+	return min(gas*uint64(100+r.config.GasBufferPercent)/100, maxGas), nil
+}
 
-	log.Println("Transaction mined in block: ", receipt.BlockNumber.Uint64())
+type TransactionInfo struct {
+	TransactionHash string
+	TransactionFee  float64
+	Fees            TransactionFees
+	// EffectiveGasPrice is the price per gas actually paid, base fee plus the tip the fee cap left room for
+	EffectiveGasPrice *big.Int
+	BlockNumber       uint64
+	BlockHash         string
+	BlockTimestamp    time.Time
+	// BatchSize is the number of calls packed into the transaction, 0 when it is not a multicall
+	BatchSize int
+}
 
-	gasUsed := receipt.GasUsed
-	effectiveGasPrice := receipt.EffectiveGasPrice
-	transactionFee := new(big.Int).Mul(big.NewInt(int64(gasUsed)), effectiveGasPrice)
+// GetNonces returns the address's nonce as of the latest block, and the highest nonce including the transactions in
+// the pool of any RPC endpoint that answers, so that a transaction broadcast through any of them counts as pending.
+func (r *ethRepository) GetNonces(ctx context.Context, address common.Address) (uint64, uint64, error) {
+	type nonces struct {
+		confirmed uint64
+		pending   uint64
+	}
+	operation := func() (nonces, error) {
+		client := r.rpc.client()
+		confirmed, err := client.NonceAt(ctx, address, nil)
+		if err != nil {
+			r.rpc.fail(client, err)
+			return nonces{}, fmt.Errorf("failed to get nonce: %w", err)
+		}
+		result := nonces{confirmed: confirmed, pending: confirmed}
+		answered := 0
+		for _, client := range r.rpc.all() {
+			pending, err := client.PendingNonceAt(ctx, address)
+			if err != nil {
+				log.Printf("Failed to get pending nonce of %s: %v", address.Hex(), err)
+				continue
+			}
+			answered++
+			result.pending = max(result.pending, pending)
+		}
+		if answered == 0 {
+			return nonces{}, fmt.Errorf("failed to get pending nonce from any RPC")
+		}
+		return result, nil
+	}
+	result, err := backoff.Retry(ctx, operation, backoff.WithBackOff(backoff.NewExponentialBackOff()))
+	if err != nil {
+		return 0, 0, err
+	}
+	return result.confirmed, result.pending, nil
+}
+
+// IsTransactionKnown reports whether any RPC endpoint has the transaction, either mined or waiting in its pool. Every
+// endpoint is asked, since the one the transaction was broadcast through is not known.
+func (r *ethRepository) IsTransactionKnown(ctx context.Context, transactionHash common.Hash) (bool, error) {
+	operation := func() (bool, error) {
+		answered := 0
+		for _, client := range r.rpc.all() {
+			_, _, err := client.TransactionByHash(ctx, transactionHash)
+			if errors.Is(err, ethereum.NotFound) {
+				answered++
+				continue
+			}
+			if err != nil {
+				log.Printf("Failed to get transaction %s: %v", transactionHash.Hex(), err)
+				continue
+			}
+			return true, nil
+		}
+		if answered == 0 {
+			return false, fmt.Errorf("failed to get transaction from any RPC")
+		}
+		return false, nil
+	}
+	return backoff.Retry(ctx, operation, backoff.WithBackOff(backoff.NewExponentialBackOff()))
+}
+
+// TransactionFees are the EIP-1559 fee caps of a transaction, in wei.
+type TransactionFees struct {
+	GasFeeCap *big.Int
+	GasTipCap *big.Int
+}
+
+// CreateTransaction runs the call with eth_call and builds it with a gas limit from gas estimation. It fails with
+// ErrPreflightRevert, wrapping the decoded revert, when the call reverts.
+func (r *ethRepository) CreateTransaction(ctx context.Context, fromAddress common.Address, toAddress common.Address, data []byte, nonce uint64, fees TransactionFees) (*types.Transaction, error) {
+	if err := r.SimulateTransaction(ctx, fromAddress, toAddress, data); err != nil {
+		return nil, err
+	}
+	gasLimit, err := r.gasLimit(ctx, fromAddress, toAddress, data)
+	if err != nil {
+		return nil, err
+	}
+	return types.NewTx(&types.DynamicFeeTx{
+		Nonce:     nonce,
+		GasTipCap: fees.GasTipCap,
+		GasFeeCap: fees.GasFeeCap,
+		Gas:       gasLimit,
+		To:        &toAddress,
+		Value:     big.NewInt(0),
+		Data:      data,
+	}), nil
+}
+
+// BroadcastTransaction sends the signed transaction to the RPC without waiting for it to be mined.
+func (r *ethRepository) BroadcastTransaction(ctx context.Context, signedTx *types.Transaction) error {
+	operation := func() (struct{}, error) {
+		err := r.rpc.broadcast(ctx, signedTx, r.config.RPCBroadcastCount)
+		// A retry re-broadcasts the same transaction, which the RPC may already have from the previous attempt
+		if err == nil || strings.Contains(err.Error(), "already known") {
+			log.Println("Transaction sent: ", signedTx.Hash().Hex())
+			return struct{}{}, nil
+		}
+		log.Println("failed to send transaction: ", err.Error())
+		if strings.Contains(err.Error(), "nonce too low") || strings.Contains(err.Error(), "replacement transaction underpriced") {
+			return struct{}{}, backoff.Permanent(fmt.Errorf("failed to send transaction: %w", err))
+		}
+		return struct{}{}, fmt.Errorf("failed to send transaction: %w", err)
+	}
+	_, err := backoff.Retry(ctx, operation, backoff.WithBackOff(backoff.NewExponentialBackOff()))
+	return err
+}
+
+// WaitForTransaction waits until one of the transactions, which share a nonce, is mined and confirmed.
+func (r *ethRepository) WaitForTransaction(ctx context.Context, transactionHashes []common.Hash, timeoutBlocks uint64) (TransactionInfo, error) {
+	startBlock, err := r.GetLatestBlock(ctx)
+	if err != nil {
+		return TransactionInfo{}, err
+	}
+	ticker := time.NewTicker(receiptPollInterval)
+	defer ticker.Stop()
+	for {
+		latestBlock, err := r.GetLatestBlock(ctx)
+		if err != nil {
+			return TransactionInfo{}, err
+		}
+		mined := false
+		for _, transactionHash := range transactionHashes {
+			client := r.rpc.client()
+			receipt, err := client.TransactionReceipt(ctx, transactionHash)
+			if errors.Is(err, ethereum.NotFound) {
+				continue
+			}
+			if err != nil {
+				r.rpc.fail(client, err)
+				log.Println("failed to get transaction receipt: ", err.Error())
+				continue
+			}
+			mined = true
+			if latestBlock < receipt.BlockNumber.Uint64()+uint64(r.config.ConfirmationBlocks) {
+				continue
+			}
+			canonical, err := r.isCanonical(ctx, receipt)
+			if err != nil {
+				log.Println("failed to check transaction block: ", err.Error())
+				continue
+			}
+			if !canonical {
+				log.Printf("Block %s of transaction %s was reorged out", receipt.BlockHash.Hex(), transactionHash.Hex())
+				continue
+			}
+			return r.transactionInfo(ctx, transactionHash, receipt)
+		}
+
+		if !mined && timeoutBlocks > 0 && latestBlock >= startBlock+timeoutBlocks {
+			return TransactionInfo{}, ErrInclusionTimeout
+		}
+		select {
+		case <-ctx.Done():
+			return TransactionInfo{}, fmt.Errorf("failed to wait for transaction to be mined: %w", ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// isCanonical reports whether the receipt's block is the block at its height on the canonical chain.
+func (r *ethRepository) isCanonical(ctx context.Context, receipt *types.Receipt) (bool, error) {
+	header, err := r.rpc.client().HeaderByNumber(ctx, receipt.BlockNumber)
+	if err != nil {
+		return false, fmt.Errorf("failed to get block header: %w", err)
+	}
+	return header.Hash() == receipt.BlockHash, nil
+}
+
+// TransactionInclusion is the canonical block a transaction was mined in.
+type TransactionInclusion struct {
+	BlockNumber    uint64
+	BlockHash      string
+	BlockTimestamp time.Time
+}
+
+// GetTransactionInclusion returns the canonical block the transaction is mined in, or nil when it is not mined.
+func (r *ethRepository) GetTransactionInclusion(ctx context.Context, transactionHash common.Hash) (*TransactionInclusion, error) {
+	operation := func() (*TransactionInclusion, error) {
+		client := r.rpc.client()
+		receipt, err := client.TransactionReceipt(ctx, transactionHash)
+		if errors.Is(err, ethereum.NotFound) {
+			return nil, nil
+		}
+		if err != nil {
+			r.rpc.fail(client, err)
+			return nil, fmt.Errorf("failed to get transaction receipt: %w", err)
+		}
+		canonical, err := r.isCanonical(ctx, receipt)
+		if err != nil {
+			return nil, err
+		}
+		if !canonical {
+			return nil, fmt.Errorf("receipt of transaction %s is in block %s, which is not canonical", transactionHash.Hex(), receipt.BlockHash.Hex())
+		}
+		blockTimestamp, err := r.GetBlockTimestamp(ctx, receipt.BlockNumber.Uint64())
+		if err != nil {
+			return nil, err
+		}
+		return &TransactionInclusion{
+			BlockNumber:    receipt.BlockNumber.Uint64(),
+			BlockHash:      receipt.BlockHash.Hex(),
+			BlockTimestamp: blockTimestamp,
+		}, nil
+	}
+	return backoff.Retry(ctx, operation, backoff.WithBackOff(backoff.NewExponentialBackOff()))
+}
+
+// IsTransactionDropped reports whether no RPC endpoint has a receipt for a transaction recorded as mined in
+// blockNumber. Only endpoints whose head is at or past blockNumber are asked, since a lagging one has not seen the
+// block yet, and every one of them has to agree. It fails when none of them answers.
+func (r *ethRepository) IsTransactionDropped(ctx context.Context, transactionHash common.Hash, blockNumber uint64) (bool, error) {
+	answered := 0
+	for _, client := range r.rpc.all() {
+		head, err := client.BlockNumber(ctx)
+		if err != nil || head < blockNumber {
+			continue
+		}
+		_, err = client.TransactionReceipt(ctx, transactionHash)
+		if errors.Is(err, ethereum.NotFound) {
+			answered++
+			continue
+		}
+		if err != nil {
+			log.Printf("Failed to get receipt of transaction %s: %v", transactionHash.Hex(), err)
+			continue
+		}
+		return false, nil
+	}
+	if answered == 0 {
+		return false, fmt.Errorf("no RPC past block %d could be asked for transaction %s", blockNumber, transactionHash.Hex())
+	}
+	return true, nil
+}
+
+func (r *ethRepository) transactionInfo(ctx context.Context, transactionHash common.Hash, receipt *types.Receipt) (TransactionInfo, error) {
+	tx, _, err := r.rpc.client().TransactionByHash(ctx, transactionHash)
+	if err != nil {
+		return TransactionInfo{}, fmt.Errorf("failed to get transaction: %w", err)
+	}
 	blockTimestamp, err := r.GetBlockTimestamp(ctx, receipt.BlockNumber.Uint64())
 	if err != nil {
 		return TransactionInfo{}, fmt.Errorf("failed to get block timestamp: %w", err)
 	}
-	return TransactionInfo{
-		TransactionHash: signedTx.Hash().Hex(),
+	transactionFee := new(big.Int).Mul(new(big.Int).SetUint64(receipt.GasUsed), receipt.EffectiveGasPrice)
+	info := TransactionInfo{
+		TransactionHash: transactionHash.Hex(),
 		TransactionFee:  utils.ConvertWeiToEther(transactionFee),
-		BlockNumber:     receipt.BlockNumber.Uint64(),
-		BlockTimestamp:  blockTimestamp,
-	}, nil
+		Fees: TransactionFees{
+			GasFeeCap: tx.GasFeeCap(),
+			GasTipCap: tx.GasTipCap(),
+		},
+		EffectiveGasPrice: receipt.EffectiveGasPrice,
+		BlockNumber:       receipt.BlockNumber.Uint64(),
+		BlockHash:         receipt.BlockHash.Hex(),
+		BlockTimestamp:    blockTimestamp,
+	}
+	if receipt.Status == types.ReceiptStatusFailed {
+		return info, fmt.Errorf("%w: %w", ErrTransactionReverted, r.replayRevert(ctx, tx, receipt))
+	}
+	log.Println("Transaction mined in block: ", receipt.BlockNumber.Uint64())
+	return info, nil
+}
+
+// replayRevert replays a reverted transaction with eth_call against the state before its block to find out why it
+// reverted. Transactions earlier in the same block are not replayed, so in rare cases the call succeeds.
+func (r *ethRepository) replayRevert(ctx context.Context, tx *types.Transaction, receipt *types.Receipt) error {
+	from, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+	if err != nil {
+		return fmt.Errorf("failed to recover sender: %w", err)
+	}
+	callMsg := ethereum.CallMsg{
+		From:  from,
+		To:    tx.To(),
+		Gas:   tx.Gas(),
+		Value: tx.Value(),
+		Data:  tx.Data(),
+	}
+	parentBlock := new(big.Int).Sub(receipt.BlockNumber, big.NewInt(1))
+	if _, err := r.rpc.client().CallContract(ctx, callMsg, parentBlock); err != nil {
+		return r.decodeRevert(err)
+	}
+	return errors.New("revert reason unknown, the call succeeds when replayed")
 }
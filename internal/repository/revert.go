@@ -0,0 +1,138 @@
+package repository
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// ContractError is a custom error the BGT contract reverted with, decoded from the revert data. Compare it against the
+// errors below with errors.Is.
+type ContractError struct {
+	Name string
+	Args []interface{}
+}
+
+var (
+	// ErrNotEnoughTime is returned when a boost or drop is activated before its delay has passed
+	ErrNotEnoughTime = &ContractError{Name: "NotEnoughTime"}
+	// ErrNotEnoughBalance is returned when queueing or cancelling more than the unboosted or queued balance
+	ErrNotEnoughBalance = &ContractError{Name: "NotEnoughBalance"}
+	// ErrNotEnoughBoostedBalance is returned when dropping more than the validator's boost
+	ErrNotEnoughBoostedBalance = &ContractError{Name: "NotEnoughBoostedBalance"}
+	// ErrInvalidPubKeyLength is returned for validator pubkeys that are not 48 bytes long
+	ErrInvalidPubKeyLength = &ContractError{Name: "InvalidPubKeyLength"}
+)
+
+func (e *ContractError) Error() string {
+	args := make([]string, len(e.Args))
+	for i, arg := range e.Args {
+		args[i] = fmt.Sprint(arg)
+	}
+	return fmt.Sprintf("BGT reverted with %s(%s)", e.Name, strings.Join(args, ", "))
+}
+
+// Is matches contract errors by name, whatever their arguments.
+func (e *ContractError) Is(target error) bool {
+	contractError, ok := target.(*ContractError)
+	return ok && contractError.Name == e.Name
+}
+
+// Retryable reports whether the same call may succeed later without anything changing but the chain moving on.
+func (e *ContractError) Retryable() bool {
+	return e.Is(ErrNotEnoughTime)
+}
+
+// isRevert reports whether an eth_call or gas estimation error is the call reverting, rather than the RPC failing to
+// run it. Reverts carry revert data, except for a bare revert, which only says so in its message.
+func isRevert(err error) bool {
+	var dataError rpc.DataError
+	if errors.As(err, &dataError) && dataError.ErrorData() != nil {
+		return true
+	}
+	return strings.Contains(err.Error(), "execution reverted")
+}
+
+// checkResult fails an activateBoost or dropBoost call that returned false, which BGT does instead of reverting when
+// the delay has not passed, with ErrPreflightRevert wrapping ErrNotEnoughTime. The calls of a multicall are checked
+// one by one.
+func (r *ethRepository) checkResult(data []byte, result []byte) error {
+	if len(data) < 4 {
+		return nil
+	}
+	method, err := r.config.BGTContract.ABI.MethodById(data[:4])
+	if err != nil {
+		return nil
+	}
+	switch method.Name {
+	case "activateBoost", "dropBoost":
+		values, err := method.Outputs.Unpack(result)
+		if err != nil {
+			return fmt.Errorf("failed to unpack %s result: %w", method.Name, err)
+		}
+		if ok, _ := values[0].(bool); !ok {
+			return fmt.Errorf("%w: %s returned false: %w", ErrPreflightRevert, method.Name, ErrNotEnoughTime)
+		}
+	case "multicall":
+		inputs, err := method.Inputs.Unpack(data[4:])
+		if err != nil {
+			return fmt.Errorf("failed to unpack multicall data: %w", err)
+		}
+		outputs, err := method.Outputs.Unpack(result)
+		if err != nil {
+			return fmt.Errorf("failed to unpack multicall result: %w", err)
+		}
+		calls, _ := inputs[0].([][]byte)
+		results, _ := outputs[0].([][]byte)
+		for i := range min(len(calls), len(results)) {
+			if err := r.checkResult(calls[i], results[i]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// decodeRevert turns the revert data carried by an RPC call error into a ContractError, or into an error with the
+// revert reason for require statements. Errors without revert data are returned unchanged.
+func (r *ethRepository) decodeRevert(err error) error {
+	var dataError rpc.DataError
+	if !errors.As(err, &dataError) {
+		return err
+	}
+	hexData, ok := dataError.ErrorData().(string)
+	if !ok {
+		return err
+	}
+	if decoded := r.decodeRevertData(common.FromHex(hexData)); decoded != nil {
+		return decoded
+	}
+	return err
+}
+
+// decodeRevertData decodes revert data against the errors of the BGT ABI. It returns nil for data it does not know.
+func (r *ethRepository) decodeRevertData(data []byte) error {
+	if len(data) < 4 {
+		return nil
+	}
+	if reason, err := abi.UnpackRevert(data); err == nil {
+		return fmt.Errorf("BGT reverted: %s", reason)
+	}
+	for _, contractError := range r.config.BGTContract.ABI.Errors {
+		if !bytes.Equal(data[:4], contractError.ID[:4]) {
+			continue
+		}
+		unpacked, err := contractError.Unpack(data)
+		if err != nil {
+			return &ContractError{Name: contractError.Name}
+		}
+		args, _ := unpacked.([]interface{})
+		return &ContractError{Name: contractError.Name, Args: args}
+	}
+	return nil
+}
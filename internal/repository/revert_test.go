@@ -0,0 +1,199 @@
+package repository
+
+import (
+	"bgt_boost/internal/config"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+const testABI = `[
+	{"type": "function", "name": "activateBoost", "inputs": [{"name": "user", "type": "address"}, {"name": "pubkey", "type": "bytes"}], "outputs": [{"name": "", "type": "bool"}]},
+	{"type": "function", "name": "queueBoost", "inputs": [{"name": "pubkey", "type": "bytes"}, {"name": "amount", "type": "uint128"}], "outputs": []},
+	{"type": "function", "name": "multicall", "inputs": [{"name": "data", "type": "bytes[]"}], "outputs": [{"name": "", "type": "bytes[]"}]},
+	{"type": "error", "name": "NotEnoughTime", "inputs": []},
+	{"type": "error", "name": "NotEnoughBalance", "inputs": []},
+	{"type": "error", "name": "InsufficientBoost", "inputs": [{"name": "available", "type": "uint256"}]}
+]`
+
+// dataError is an RPC error carrying revert data, like the ones go-ethereum returns for reverted calls.
+type dataError struct {
+	message string
+	data    interface{}
+}
+
+func (e dataError) Error() string          { return e.message }
+func (e dataError) ErrorData() interface{} { return e.data }
+
+func testRevertRepository(t *testing.T) (*ethRepository, abi.ABI) {
+	t.Helper()
+	contractABI, err := abi.JSON(strings.NewReader(testABI))
+	if err != nil {
+		t.Fatalf("failed to parse ABI: %v", err)
+	}
+	return &ethRepository{config: &config.Config{BGTContract: config.Contract{ABI: contractABI}}}, contractABI
+}
+
+// selector returns the 4 byte selector of the ABI error.
+func selector(contractABI abi.ABI, name string) []byte {
+	id := contractABI.Errors[name].ID
+	return id[:4]
+}
+
+func revertReasonData(t *testing.T, reason string) []byte {
+	t.Helper()
+	stringType, err := abi.NewType("string", "", nil)
+	if err != nil {
+		t.Fatalf("failed to create string type: %v", err)
+	}
+	packed, err := abi.Arguments{{Type: stringType}}.Pack(reason)
+	if err != nil {
+		t.Fatalf("failed to pack revert reason: %v", err)
+	}
+	return append([]byte{0x08, 0xc3, 0x79, 0xa0}, packed...)
+}
+
+func TestDecodeRevertData(t *testing.T) {
+	r, contractABI := testRevertRepository(t)
+	uint256Type, err := abi.NewType("uint256", "", nil)
+	if err != nil {
+		t.Fatalf("failed to create uint256 type: %v", err)
+	}
+	insufficientArgs, err := abi.Arguments{{Type: uint256Type}}.Pack(big.NewInt(42))
+	if err != nil {
+		t.Fatalf("failed to pack error arguments: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+		// want is the expected error message, empty when the data is not decoded
+		want    string
+		wantErr error
+	}{
+		{"no data", nil, "", nil},
+		{"shorter than a selector", []byte{0x01, 0x02, 0x03}, "", nil},
+		{"unknown selector", []byte{0xde, 0xad, 0xbe, 0xef}, "", nil},
+		{"custom error", selector(contractABI, "NotEnoughTime"), "BGT reverted with NotEnoughTime()", ErrNotEnoughTime},
+		{"another custom error", selector(contractABI, "NotEnoughBalance"), "BGT reverted with NotEnoughBalance()", ErrNotEnoughBalance},
+		{"custom error with arguments", append(selector(contractABI, "InsufficientBoost"), insufficientArgs...), "BGT reverted with InsufficientBoost(42)", nil},
+		{"custom error with missing arguments", selector(contractABI, "InsufficientBoost"), "BGT reverted with InsufficientBoost()", nil},
+		{"revert reason", revertReasonData(t, "not allowed"), "BGT reverted: not allowed", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := r.decodeRevertData(tt.data)
+			if tt.want == "" {
+				if got != nil {
+					t.Fatalf("decodeRevertData() = %v, want nil", got)
+				}
+				return
+			}
+			if got == nil {
+				t.Fatalf("decodeRevertData() = nil, want %q", tt.want)
+			}
+			if got.Error() != tt.want {
+				t.Errorf("decodeRevertData() = %q, want %q", got.Error(), tt.want)
+			}
+			if tt.wantErr != nil && !errors.Is(got, tt.wantErr) {
+				t.Errorf("decodeRevertData() = %v, want it to match %v", got, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDecodeRevert(t *testing.T) {
+	r, contractABI := testRevertRepository(t)
+	notEnoughTime := hexutil.Encode(selector(contractABI, "NotEnoughTime"))
+
+	err := r.decodeRevert(fmt.Errorf("call failed: %w", dataError{"execution reverted", notEnoughTime}))
+	if !errors.Is(err, ErrNotEnoughTime) {
+		t.Errorf("decodeRevert() = %v, want %v", err, ErrNotEnoughTime)
+	}
+	var contractError *ContractError
+	if !errors.As(err, &contractError) || !contractError.Retryable() {
+		t.Errorf("decodeRevert() = %v, want a retryable ContractError", err)
+	}
+
+	// Errors the contract does not define, and errors without revert data, are left as they are.
+	plain := errors.New("connection refused")
+	if err := r.decodeRevert(plain); err != plain {
+		t.Errorf("decodeRevert() = %v, want %v", err, plain)
+	}
+	for _, data := range []interface{}{"0xdeadbeef", 42} {
+		unknown := dataError{"execution reverted", data}
+		if err := r.decodeRevert(unknown); err.Error() != "execution reverted" {
+			t.Errorf("decodeRevert() with data %v = %q, want the RPC error", data, err)
+		}
+	}
+}
+
+func TestIsRevert(t *testing.T) {
+	reverts := []error{
+		dataError{"execution reverted: custom error", "0x12345678"},
+		fmt.Errorf("estimate failed: %w", dataError{"reverted", "0x12345678"}),
+		errors.New("execution reverted"),
+	}
+	for _, err := range reverts {
+		if !isRevert(err) {
+			t.Errorf("isRevert(%v) = false, want true", err)
+		}
+	}
+
+	failures := []error{
+		dataError{"header not found", nil},
+		errors.New("context deadline exceeded"),
+		errors.New("dial tcp: connection refused"),
+	}
+	for _, err := range failures {
+		if isRevert(err) {
+			t.Errorf("isRevert(%v) = true, want false", err)
+		}
+	}
+}
+
+func TestCheckResult(t *testing.T) {
+	r, contractABI := testRevertRepository(t)
+	pack := func(name string, args ...interface{}) []byte {
+		data, err := contractABI.Pack(name, args...)
+		if err != nil {
+			t.Fatalf("failed to pack %s: %v", name, err)
+		}
+		return data
+	}
+	returned := func(ok bool) []byte {
+		result, err := contractABI.Methods["activateBoost"].Outputs.Pack(ok)
+		if err != nil {
+			t.Fatalf("failed to pack result: %v", err)
+		}
+		return result
+	}
+	activate := pack("activateBoost", common.HexToAddress("0x01"), []byte{0xaa})
+	queue := pack("queueBoost", []byte{0xaa}, big.NewInt(1))
+
+	if err := r.checkResult(activate, returned(true)); err != nil {
+		t.Errorf("activateBoost returning true: %v", err)
+	}
+	err := r.checkResult(activate, returned(false))
+	if !errors.Is(err, ErrPreflightRevert) || !errors.Is(err, ErrNotEnoughTime) {
+		t.Errorf("activateBoost returning false: got %v, want a pre-flight NotEnoughTime", err)
+	}
+	if err := r.checkResult(queue, nil); err != nil {
+		t.Errorf("queueBoost: %v", err)
+	}
+
+	multicall := pack("multicall", [][]byte{queue, activate})
+	results, err := contractABI.Methods["multicall"].Outputs.Pack([][]byte{{}, returned(false)})
+	if err != nil {
+		t.Fatalf("failed to pack multicall result: %v", err)
+	}
+	if err := r.checkResult(multicall, results); !errors.Is(err, ErrNotEnoughTime) {
+		t.Errorf("multicall with an activateBoost returning false: got %v, want NotEnoughTime", err)
+	}
+}
@@ -0,0 +1,242 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// rpcCheckTimeout bounds how long a health check waits for an endpoint.
+const rpcCheckTimeout = 5 * time.Second
+
+// rpcMaxCallErrors is how many failed calls take an endpoint out of rotation until its next check passes.
+const rpcMaxCallErrors = 3
+
+// rpcEndpoint is one RPC provider with the health seen by the last checks.
+type rpcEndpoint struct {
+	url    string
+	client *ethclient.Client
+	// latency is a moving average of the time the health check takes
+	latency time.Duration
+	head    uint64
+	// failures counts the health checks that failed in a row, errors the calls that failed since the last check
+	failures int
+	errors   int
+	// inRotation is whether reads may go to the endpoint
+	inRotation bool
+}
+
+// RPCPool spreads calls over the healthy RPC endpoints, preferring the ones with the fewest errors.
+type RPCPool struct {
+	mutex     sync.RWMutex
+	endpoints []*rpcEndpoint
+	maxLag    uint64
+	done      chan struct{}
+}
+
+// DialRPCPool connects to every URL, checks them once and keeps checking them every interval until Close. URLs that
+// cannot be dialed are left out, and it fails only when none can.
+func DialRPCPool(urls []string, maxLag uint64, interval time.Duration) (*RPCPool, error) {
+	pool := &RPCPool{
+		maxLag: maxLag,
+		done:   make(chan struct{}),
+	}
+	var errs []error
+	for _, url := range urls {
+		client, err := ethclient.Dial(url)
+		if err != nil {
+			log.Printf("Failed to dial RPC %s, leaving it out: %v", url, err)
+			errs = append(errs, fmt.Errorf("failed to dial %s: %w", url, err))
+			continue
+		}
+		pool.endpoints = append(pool.endpoints, &rpcEndpoint{url: url, client: client, inRotation: true})
+	}
+	if len(pool.endpoints) == 0 {
+		return nil, errors.Join(append([]error{errors.New("no RPC could be dialed")}, errs...)...)
+	}
+	pool.check()
+	go pool.run(interval)
+	return pool, nil
+}
+
+func (p *RPCPool) Close() {
+	select {
+	case <-p.done:
+	default:
+		close(p.done)
+	}
+	for _, endpoint := range p.endpoints {
+		endpoint.client.Close()
+	}
+}
+
+func (p *RPCPool) run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+			p.check()
+		}
+	}
+}
+
+// check measures every endpoint's head and latency and updates the rotation.
+func (p *RPCPool) check() {
+	type result struct {
+		head    uint64
+		latency time.Duration
+		err     error
+	}
+	results := make([]result, len(p.endpoints))
+	var wg sync.WaitGroup
+	for i, endpoint := range p.endpoints {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), rpcCheckTimeout)
+			defer cancel()
+			start := time.Now()
+			head, err := endpoint.client.BlockNumber(ctx)
+			results[i] = result{head: head, latency: time.Since(start), err: err}
+		}()
+	}
+	wg.Wait()
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	var bestHead uint64
+	for i, endpoint := range p.endpoints {
+		if results[i].err != nil {
+			endpoint.failures++
+			continue
+		}
+		endpoint.failures = 0
+		endpoint.errors = 0
+		endpoint.head = results[i].head
+		if endpoint.latency == 0 {
+			endpoint.latency = results[i].latency
+		} else {
+			endpoint.latency = (endpoint.latency*3 + results[i].latency) / 4
+		}
+		bestHead = max(bestHead, endpoint.head)
+	}
+	for i, endpoint := range p.endpoints {
+		inRotation := endpoint.failures == 0 && endpoint.head+p.maxLag >= bestHead
+		if inRotation == endpoint.inRotation {
+			continue
+		}
+		endpoint.inRotation = inRotation
+		switch {
+		case inRotation:
+			log.Printf("RPC %s is back in rotation at block %d", endpoint.url, endpoint.head)
+		case results[i].err != nil:
+			log.Printf("RPC %s taken out of rotation: %v", endpoint.url, results[i].err)
+		default:
+			log.Printf("RPC %s taken out of rotation: head %d is %d blocks behind", endpoint.url, endpoint.head, bestHead-endpoint.head)
+		}
+	}
+}
+
+// client returns the endpoint in rotation with the fewest failed calls, and the fastest of those. When none is in
+// rotation it falls back to the endpoint with the fewest failed checks, and then the highest head.
+func (p *RPCPool) client() *ethclient.Client {
+	return p.clients(1)[0]
+}
+
+// clients returns up to count endpoints, the ones in rotation first and the fastest of those first.
+func (p *RPCPool) clients(count int) []*ethclient.Client {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	ranked := make([]*rpcEndpoint, len(p.endpoints))
+	copy(ranked, p.endpoints)
+	better := func(a *rpcEndpoint, b *rpcEndpoint) bool {
+		if a.inRotation != b.inRotation {
+			return a.inRotation
+		}
+		if !a.inRotation && a.failures != b.failures {
+			return a.failures < b.failures
+		}
+		if !a.inRotation && a.head != b.head {
+			return a.head > b.head
+		}
+		if a.errors != b.errors {
+			return a.errors < b.errors
+		}
+		return a.latency < b.latency
+	}
+	sort.SliceStable(ranked, func(i, j int) bool { return better(ranked[i], ranked[j]) })
+
+	clients := []*ethclient.Client{ranked[0].client}
+	for _, endpoint := range ranked[1:] {
+		if len(clients) >= count || !endpoint.inRotation {
+			break
+		}
+		clients = append(clients, endpoint.client)
+	}
+	return clients
+}
+
+// fail counts a failed call against the endpoint of client, which then ranks below the endpoints with fewer failed
+// calls and leaves the rotation after rpcMaxCallErrors of them. Calls cancelled by their context do not count.
+func (p *RPCPool) fail(client *ethclient.Client, err error) {
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	for _, endpoint := range p.endpoints {
+		if endpoint.client != client {
+			continue
+		}
+		endpoint.errors++
+		if endpoint.inRotation && endpoint.errors >= rpcMaxCallErrors {
+			endpoint.inRotation = false
+			log.Printf("RPC %s taken out of rotation after %d failed calls: %v", endpoint.url, endpoint.errors, err)
+		}
+		return
+	}
+}
+
+// all returns every endpoint, in or out of rotation.
+func (p *RPCPool) all() []*ethclient.Client {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	clients := make([]*ethclient.Client, len(p.endpoints))
+	for i, endpoint := range p.endpoints {
+		clients[i] = endpoint.client
+	}
+	return clients
+}
+
+// broadcast sends the transaction through up to count endpoints at once. It succeeds when any of them accepts it or
+// already has it, and otherwise returns the error of the first endpoint.
+func (p *RPCPool) broadcast(ctx context.Context, signedTx *types.Transaction, count int) error {
+	clients := p.clients(count)
+	errs := make([]error, len(clients))
+	var wg sync.WaitGroup
+	for i, client := range clients {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = client.SendTransaction(ctx, signedTx)
+		}()
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err == nil || strings.Contains(err.Error(), "already known") {
+			return err
+		}
+	}
+	return errs[0]
+}
@@ -0,0 +1,283 @@
+package services
+
+import (
+	"bgt_boost/internal/models"
+	"bgt_boost/internal/repository"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	methodMulticall       = "multicall"
+	methodQueueBoost      = "queueBoost"
+	methodActivateBoost   = "activateBoost"
+	methodQueueDropBoost  = "queueDropBoost"
+	methodDropBoost       = "dropBoost"
+	methodCancelBoost     = "cancelBoost"
+	methodCancelDropBoost = "cancelDropBoost"
+)
+
+// action is a single BGT contract call the engine decided to make for a validator.
+type action struct {
+	Method    string
+	Validator models.Validator
+	Amount    *big.Int
+	Data      []byte
+
+	// dropBoostRequestIDs are the drop boost requests settled by a queueDropBoost or dropBoost action
+	dropBoostRequestIDs []primitive.ObjectID
+	// cancelsQueue is set on cancels of the validator's whole queue
+	cancelsQueue bool
+}
+
+func (s *boostService) newAction(method string, validator models.Validator, amount *big.Int, args ...interface{}) (action, error) {
+	data, err := s.config.BGTContract.ABI.Pack(method, args...)
+	if err != nil {
+		return action{}, fmt.Errorf("failed to pack data: %w", err)
+	}
+	return action{
+		Method:    method,
+		Validator: validator,
+		Amount:    amount,
+		Data:      data,
+	}, nil
+}
+
+// deferred reports the action as held back until a later run for the given reason.
+func (a action) deferred(reason error) DeferredAction {
+	return DeferredAction{
+		Method: a.Method,
+		Amount: a.Amount.String(),
+		Reason: reason.Error(),
+	}
+}
+
+// retryLater reports whether the call was rejected only because it came too early, so that it is retried on a later
+// run instead of failing the validator.
+func retryLater(err error) bool {
+	var contractError *repository.ContractError
+	return errors.As(err, &contractError) && contractError.Retryable()
+}
+
+func (a action) status() ValidatorStatus {
+	switch a.Method {
+	case methodQueueBoost:
+		return ValidatorStatusQueued
+	case methodActivateBoost:
+		return ValidatorStatusActivated
+	case methodQueueDropBoost:
+		return ValidatorStatusDropQueued
+	case methodDropBoost:
+		return ValidatorStatusDropped
+	}
+	return ValidatorStatusSkipped
+}
+
+// executeActions sends the operator's actions, packed into a multicall when enabled, or one transaction each.
+func (s *boostService) executeActions(ctx context.Context, operator models.Operator, actions []action, reports map[string]*ValidatorReport) {
+	if s.config.UseMulticall && len(actions) > 1 {
+		s.executeMulticall(ctx, operator, actions, reports)
+		return
+	}
+	s.executeEach(ctx, operator, actions, reports)
+}
+
+// executeEach sends one transaction per action. Later calls for a validator whose earlier call has to be retried
+// could undo it, such as a queueBoost resetting the boost waiting for activation, so they wait for the next run too.
+func (s *boostService) executeEach(ctx context.Context, operator models.Operator, actions []action, reports map[string]*ValidatorReport) {
+	retrying := make(map[string]error)
+	for _, a := range actions {
+		report := reports[a.Validator.Pubkey]
+		if report.Status == ValidatorStatusFailed || report.Status == ValidatorStatusReverted {
+			continue
+		}
+		if err, ok := retrying[a.Validator.Pubkey]; ok {
+			report.addDeferred([]DeferredAction{a.deferred(err)})
+			continue
+		}
+		if err := s.executeAction(ctx, operator, a, report); err != nil {
+			if retryLater(err) {
+				log.Printf("Deferring %s for validator %s: %v", a.Method, a.Validator.Pubkey, err)
+				report.addDeferred([]DeferredAction{a.deferred(err)})
+				retrying[a.Validator.Pubkey] = err
+				continue
+			}
+			log.Printf("Failed to process validator %s: %v", a.Validator.Pubkey, err)
+			report.fail(err)
+		}
+	}
+}
+
+func (s *boostService) executeAction(ctx context.Context, operator models.Operator, a action, report *ValidatorReport) error {
+	log.Printf("Sending %s for validator %s: %s", a.Method, a.Validator.Pubkey, a.Amount.String())
+	transactionInfo, err := s.send(ctx, operator, a.Data, []action{a})
+	if err != nil {
+		return err
+	}
+	log.Printf("Sent %s: %s", a.Method, transactionInfo.TransactionHash)
+	report.addAction(a.status(), a.Method, a.Amount.String(), transactionInfo.TransactionHash)
+	if err := s.recordAction(ctx, a, transactionInfo); err != nil {
+		return err
+	}
+	s.markTransactionRecorded(ctx, transactionInfo)
+	return nil
+}
+
+// executeMulticall packs all of an operator's actions into a single multicall transaction and records each inner
+// call against the shared transaction hash. The BGT multicall is atomic, so when it reverts its calls are checked one
+// by one and only the ones that revert on their own are held back.
+func (s *boostService) executeMulticall(ctx context.Context, operator models.Operator, actions []action, reports map[string]*ValidatorReport) {
+	calls := make([][]byte, len(actions))
+	for i, a := range actions {
+		calls[i] = a.Data
+	}
+	data, err := s.config.BGTContract.ABI.Pack(methodMulticall, calls)
+	if err != nil {
+		failActions(actions, reports, fmt.Errorf("failed to pack data: %w", err))
+		return
+	}
+
+	log.Printf("Sending multicall with %d calls for operator %s", len(actions), operator.Address)
+	transactionInfo, err := s.send(ctx, operator, data, actions)
+	if err != nil {
+		if errors.Is(err, repository.ErrGasLimitExceeded) {
+			first, second := splitActions(actions)
+			log.Printf("Multicall with %d calls for operator %s is above its gas limit, sending %d and %d calls instead", len(actions), operator.Address, len(first), len(second))
+			s.executeActions(ctx, operator, first, reports)
+			s.executeActions(ctx, operator, second, reports)
+			return
+		}
+		if errors.Is(err, repository.ErrPreflightRevert) || errors.Is(err, repository.ErrTransactionReverted) {
+			log.Printf("Multicall for operator %s reverted, checking its calls one by one: %v", operator.Address, err)
+			s.executePreflighted(ctx, operator, actions, reports)
+			return
+		}
+		log.Printf("Failed to send multicall for operator %s: %v", operator.Address, err)
+		failActions(actions, reports, err)
+		return
+	}
+	log.Printf("Sent multicall: %s", transactionInfo.TransactionHash)
+
+	for _, a := range actions {
+		reports[a.Validator.Pubkey].addAction(a.status(), a.Method, a.Amount.String(), transactionInfo.TransactionHash)
+	}
+	recorded := true
+	for i, err := range s.recordActions(ctx, actions, transactionInfo) {
+		if err != nil {
+			reports[actions[i].Validator.Pubkey].fail(err)
+			recorded = false
+		}
+	}
+	if recorded {
+		s.markTransactionRecorded(ctx, transactionInfo)
+	}
+}
+
+// splitActions splits a multicall's actions in two halves, moving the split to the nearest change of validator so
+// that a validator's calls stay in the same transaction whenever possible.
+func splitActions(actions []action) ([]action, []action) {
+	middle := len(actions) / 2
+	for offset := 0; offset < len(actions); offset++ {
+		for _, i := range []int{middle - offset, middle + offset} {
+			if i > 0 && i < len(actions) && actions[i-1].Validator.Pubkey != actions[i].Validator.Pubkey {
+				return actions[:i], actions[i:]
+			}
+		}
+	}
+	return actions[:middle], actions[middle:]
+}
+
+// executePreflighted runs each call of a reverted multicall on its own with eth_call. Calls that revert are deferred
+// or reverted like single transactions, along with the later calls for the same validator, and the rest are sent
+// again. When every call passes on its own, only their combination reverts, so they are sent one by one.
+func (s *boostService) executePreflighted(ctx context.Context, operator models.Operator, actions []action, reports map[string]*ValidatorReport) {
+	from := common.HexToAddress(operator.Address)
+	held := make(map[string]error)
+	var passing []action
+	for _, a := range actions {
+		report := reports[a.Validator.Pubkey]
+		if err, ok := held[a.Validator.Pubkey]; ok {
+			if retryLater(err) {
+				report.addDeferred([]DeferredAction{a.deferred(err)})
+			}
+			continue
+		}
+		err := (*s.ethRepository).SimulateTransaction(ctx, from, s.config.BGTContract.Address, a.Data)
+		if err == nil {
+			passing = append(passing, a)
+			continue
+		}
+		held[a.Validator.Pubkey] = err
+		if retryLater(err) {
+			log.Printf("Deferring %s for validator %s: %v", a.Method, a.Validator.Pubkey, err)
+			report.addDeferred([]DeferredAction{a.deferred(err)})
+			continue
+		}
+		log.Printf("Failed to process validator %s: %v", a.Validator.Pubkey, err)
+		report.fail(err)
+	}
+
+	if len(passing) == len(actions) {
+		s.executeEach(ctx, operator, actions, reports)
+		return
+	}
+	s.executeActions(ctx, operator, passing, reports)
+}
+
+// recordActions writes the records of every call a mined transaction made. The inner calls of a multicall are each
+// recorded with an equal part of the fee so that totals stay correct. It returns the error of each action, nil for
+// the ones that were recorded.
+func (s *boostService) recordActions(ctx context.Context, actions []action, transactionInfo repository.TransactionInfo) []error {
+	if len(actions) > 1 {
+		transactionInfo.TransactionFee /= float64(len(actions))
+		transactionInfo.BatchSize = len(actions)
+	}
+	errs := make([]error, len(actions))
+	for i, a := range actions {
+		if errs[i] = s.recordAction(ctx, a, transactionInfo); errs[i] != nil {
+			log.Printf("Failed to record %s for validator %s: %v", a.Method, a.Validator.Pubkey, errs[i])
+		}
+	}
+	return errs
+}
+
+func failActions(actions []action, reports map[string]*ValidatorReport, err error) {
+	for _, a := range actions {
+		reports[a.Validator.Pubkey].fail(err)
+	}
+}
+
+func (s *boostService) recordAction(ctx context.Context, a action, transactionInfo repository.TransactionInfo) error {
+	switch a.Method {
+	case methodQueueBoost:
+		s.refreshActivation(ctx, a.Validator)
+		return s.recordQueueBoost(ctx, a.Validator, a.Amount, transactionInfo)
+	case methodActivateBoost:
+		s.refreshActivation(ctx, a.Validator)
+		return s.recordActivateBoost(ctx, a.Validator, a.Amount, transactionInfo)
+	case methodQueueDropBoost:
+		if err := (*s.dbRepository).MarkDropBoostRequestsQueued(ctx, a.dropBoostRequestIDs, transactionInfo.TransactionHash); err != nil {
+			return err
+		}
+		return s.recordQueueDropBoost(ctx, a.Validator, a.Amount, transactionInfo)
+	case methodDropBoost:
+		if err := (*s.dbRepository).MarkDropBoostRequestsDropped(ctx, a.dropBoostRequestIDs, transactionInfo.TransactionHash); err != nil {
+			return err
+		}
+		return s.recordDropBoost(ctx, a.Validator, a.Amount, transactionInfo)
+	case methodCancelBoost:
+		s.refreshActivation(ctx, a.Validator)
+		_, err := s.recordCancelBoost(ctx, a.Validator, a.Amount, a.cancelsQueue, transactionInfo)
+		return err
+	case methodCancelDropBoost:
+		_, err := s.recordCancelDropBoost(ctx, a.Validator, a.Amount, a.cancelsQueue, transactionInfo)
+		return err
+	}
+	return fmt.Errorf("unknown action: %s", a.Method)
+}
@@ -0,0 +1,293 @@
+package services
+
+import (
+	"bgt_boost/internal/models"
+	"bgt_boost/internal/repository"
+	"context"
+	"log"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	// activationRetryBlocks is how long a due activation that could not be sent waits before it is tried again
+	activationRetryBlocks = 10
+	// blockTimeSampleSize is how many recent blocks the average block time is measured over
+	blockTimeSampleSize = 100
+)
+
+type UpcomingActivation struct {
+	models.ScheduledActivation
+	BlocksRemaining uint64    `json:"blocksRemaining"`
+	EstimatedTime   time.Time `json:"estimatedTime"`
+}
+
+// activationSchedule is the in-memory copy of the scheduled_activations collection, keyed by validator pubkey.
+type activationSchedule struct {
+	mu          sync.Mutex
+	activations map[string]models.ScheduledActivation
+	retryBlocks map[string]uint64
+}
+
+// set stores the activation and reports whether it changed.
+func (a *activationSchedule) set(activation models.ScheduledActivation) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.activations == nil {
+		a.activations = make(map[string]models.ScheduledActivation)
+		a.retryBlocks = make(map[string]uint64)
+	}
+	if current, ok := a.activations[activation.ValidatorPubkey]; ok && current == activation {
+		return false
+	}
+	a.activations[activation.ValidatorPubkey] = activation
+	delete(a.retryBlocks, activation.ValidatorPubkey)
+	return true
+}
+
+// remove deletes the validator's activation and reports whether there was one.
+func (a *activationSchedule) remove(pubkey string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if _, ok := a.activations[pubkey]; !ok {
+		return false
+	}
+	delete(a.activations, pubkey)
+	delete(a.retryBlocks, pubkey)
+	return true
+}
+
+func (a *activationSchedule) postpone(pubkey string, block uint64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if _, ok := a.activations[pubkey]; ok {
+		a.retryBlocks[pubkey] = block
+	}
+}
+
+// due returns the activations that are eligible at block and not waiting for a retry.
+func (a *activationSchedule) due(block uint64) []models.ScheduledActivation {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var due []models.ScheduledActivation
+	for pubkey, activation := range a.activations {
+		if activation.EligibleBlock <= block && a.retryBlocks[pubkey] <= block {
+			due = append(due, activation)
+		}
+	}
+	return due
+}
+
+func (a *activationSchedule) all() []models.ScheduledActivation {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	activations := make([]models.ScheduledActivation, 0, len(a.activations))
+	for _, activation := range a.activations {
+		activations = append(activations, activation)
+	}
+	sort.Slice(activations, func(i, j int) bool {
+		return activations[i].EligibleBlock < activations[j].EligibleBlock
+	})
+	return activations
+}
+
+// LoadActivationSchedule reads the persisted schedule into memory, so that activations queued before a restart are
+// sent without waiting for the next boost run.
+func (s *boostService) LoadActivationSchedule(ctx context.Context) error {
+	activations, err := (*s.dbRepository).GetScheduledActivations(ctx)
+	if err != nil {
+		return err
+	}
+	for _, activation := range activations {
+		s.activations.set(activation)
+	}
+	log.Printf("Loaded %d scheduled activations", len(activations))
+	return nil
+}
+
+// scheduleActivation keeps the validator's scheduled activation in line with its boostedQueue. The eligible block is
+// the first block after the activation delay has passed.
+func (s *boostService) scheduleActivation(ctx context.Context, validator models.Validator, boostedQueue repository.BoostedQueue, activateBoostDelay uint64) {
+	if boostedQueue.Balance.Sign() <= 0 {
+		if s.activations.remove(validator.Pubkey) {
+			if err := (*s.dbRepository).DeleteScheduledActivation(ctx, validator.Pubkey); err != nil {
+				log.Printf("Failed to delete scheduled activation of validator %s: %v", validator.Pubkey, err)
+			}
+		}
+		return
+	}
+
+	activation := models.ScheduledActivation{
+		ValidatorPubkey:  validator.Pubkey,
+		OperatorAddress:  validator.OperatorAddress,
+		Amount:           boostedQueue.Balance.String(),
+		QueueBlockNumber: boostedQueue.BlockNumber,
+		EligibleBlock:    boostedQueue.BlockNumber + activateBoostDelay + 1,
+	}
+	if !s.activations.set(activation) {
+		return
+	}
+	log.Printf("Scheduled activation of %s for validator %s at block %d", activation.Amount, validator.Pubkey, activation.EligibleBlock)
+	if err := (*s.dbRepository).SaveScheduledActivation(ctx, activation); err != nil {
+		log.Printf("Failed to save scheduled activation of validator %s: %v", validator.Pubkey, err)
+	}
+}
+
+// refreshActivation reads the validator's boostedQueue after a transaction changed it and reschedules its activation.
+func (s *boostService) refreshActivation(ctx context.Context, validator models.Validator) {
+	boostedQueue, err := (*s.ethRepository).GetBoostedQueue(ctx, common.HexToAddress(validator.OperatorAddress), validator.Pubkey)
+	if err != nil {
+		log.Printf("Failed to refresh scheduled activation of validator %s: %v", validator.Pubkey, err)
+		return
+	}
+	activateBoostDelay, err := (*s.ethRepository).GetActivateBoostDelay(ctx)
+	if err != nil {
+		log.Printf("Failed to refresh scheduled activation of validator %s: %v", validator.Pubkey, err)
+		return
+	}
+	s.scheduleActivation(ctx, validator, boostedQueue, activateBoostDelay)
+}
+
+// RunActivationScheduler sends activateBoost for every scheduled activation as soon as the chain reaches its eligible
+// block, until ctx is cancelled. Blocks that arrive while activations are still being sent are skipped.
+func (s *boostService) RunActivationScheduler(ctx context.Context) {
+	heads := make(chan uint64)
+	go watchHeads(ctx, s.ethRepository, s.config.EventPollSeconds, heads)
+
+	var running atomic.Bool
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case head := <-heads:
+			if running.Load() {
+				continue
+			}
+			due := s.activations.due(head)
+			if len(due) == 0 {
+				continue
+			}
+			running.Store(true)
+			go func() {
+				defer running.Store(false)
+				log.Printf("Activating %d scheduled boosts at block %d", len(due), head)
+				forEachConcurrently(len(due), s.config.BoostConcurrency, func(i int) {
+					s.activateScheduled(ctx, due[i], head)
+				})
+			}()
+		}
+	}
+}
+
+func (s *boostService) activateScheduled(ctx context.Context, activation models.ScheduledActivation, head uint64) {
+	validator, err := (*s.dbRepository).GetValidator(ctx, activation.ValidatorPubkey)
+	if err == mongo.ErrNoDocuments {
+		if s.activations.remove(activation.ValidatorPubkey) {
+			if err := (*s.dbRepository).DeleteScheduledActivation(ctx, activation.ValidatorPubkey); err != nil {
+				log.Printf("Failed to delete scheduled activation of validator %s: %v", activation.ValidatorPubkey, err)
+			}
+		}
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to get validator %s for scheduled activation: %v", activation.ValidatorPubkey, err)
+		s.activations.postpone(activation.ValidatorPubkey, head+activationRetryBlocks)
+		return
+	}
+	operator, err := s.getOperator(ctx, validator.OperatorAddress)
+	if err != nil {
+		log.Printf("Failed to get operator %s for scheduled activation: %v", validator.OperatorAddress, err)
+		s.activations.postpone(validator.Pubkey, head+activationRetryBlocks)
+		return
+	}
+	if !operator.Enabled {
+		s.activations.postpone(validator.Pubkey, head+activationRetryBlocks)
+		return
+	}
+
+	unlock := s.operatorLocks.lock(operator.Address)
+	defer unlock()
+	state, err := s.getRunState(ctx)
+	if err != nil {
+		log.Printf("Failed to get chain state for scheduled activation of validator %s: %v", validator.Pubkey, err)
+		s.activations.postpone(validator.Pubkey, head+activationRetryBlocks)
+		return
+	}
+	boostedQueue, err := (*s.ethRepository).GetBoostedQueue(ctx, common.HexToAddress(validator.OperatorAddress), validator.Pubkey)
+	if err != nil {
+		log.Printf("Failed to get boosted queue for scheduled activation of validator %s: %v", validator.Pubkey, err)
+		s.activations.postpone(validator.Pubkey, head+activationRetryBlocks)
+		return
+	}
+	// The queue may have been activated, cancelled or reset since the activation was scheduled
+	s.scheduleActivation(ctx, validator, boostedQueue, state.ActivateBoostDelay)
+	a, err := s.planActivateBoost(validator, boostedQueue, state)
+	if err != nil || a == nil {
+		return
+	}
+
+	d := decision{Actions: []action{*a}}
+	s.applyGasGuard(ctx, &d, operator, state)
+	if len(d.Deferred) > 0 {
+		log.Printf("Deferred scheduled activation of validator %s: %s", validator.Pubkey, d.Deferred[0].Reason)
+		s.activations.postpone(validator.Pubkey, head+activationRetryBlocks)
+		return
+	}
+	report := ValidatorReport{
+		Pubkey:          validator.Pubkey,
+		OperatorAddress: validator.OperatorAddress,
+		Status:          ValidatorStatusSkipped,
+	}
+	if err := s.executeAction(ctx, operator, *a, &report); err != nil {
+		log.Printf("Failed scheduled activation of validator %s: %v", validator.Pubkey, err)
+		s.activations.postpone(validator.Pubkey, head+activationRetryBlocks)
+	}
+}
+
+// UpcomingActivations lists the scheduled activations with the time they are expected to become eligible, estimated
+// from the average time of recent blocks.
+func (s *boostService) UpcomingActivations(ctx context.Context) ([]UpcomingActivation, error) {
+	head, err := (*s.ethRepository).GetLatestBlock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	headTime, err := (*s.ethRepository).GetBlockTimestamp(ctx, head)
+	if err != nil {
+		return nil, err
+	}
+	blockTime, err := s.averageBlockTime(ctx, head, headTime)
+	if err != nil {
+		return nil, err
+	}
+
+	activations := s.activations.all()
+	upcoming := make([]UpcomingActivation, 0, len(activations))
+	for _, activation := range activations {
+		var remaining uint64
+		if activation.EligibleBlock > head {
+			remaining = activation.EligibleBlock - head
+		}
+		upcoming = append(upcoming, UpcomingActivation{
+			ScheduledActivation: activation,
+			BlocksRemaining:     remaining,
+			EstimatedTime:       headTime.Add(time.Duration(remaining) * blockTime),
+		})
+	}
+	return upcoming, nil
+}
+
+func (s *boostService) averageBlockTime(ctx context.Context, head uint64, headTime time.Time) (time.Duration, error) {
+	if head < blockTimeSampleSize {
+		return 0, nil
+	}
+	sampleTime, err := (*s.ethRepository).GetBlockTimestamp(ctx, head-blockTimeSampleSize)
+	if err != nil {
+		return 0, err
+	}
+	return headTime.Sub(sampleTime) / blockTimeSampleSize, nil
+}
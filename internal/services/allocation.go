@@ -0,0 +1,76 @@
+package services
+
+import (
+	"bgt_boost/internal/models"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// CheckAllocation validates the allocation percentages of one operator's validators. Either no validator sets a
+// percentage, in which case the unboosted balance is split equally, or every validator sets one and they add up to
+// exactly 100.
+func CheckAllocation(validators []models.Validator) error {
+	total, weighted := allocationTotal(validators)
+	if !weighted {
+		return nil
+	}
+	for _, validator := range validators {
+		if validator.AllocationPercentage == 0 {
+			return fmt.Errorf("validator %s of operator %s has no allocation percentage while other validators of the operator have one", validator.Pubkey, validator.OperatorAddress)
+		}
+	}
+	if total != 100 {
+		return fmt.Errorf("allocation percentages for operator %s add up to %d%%, expected 100%%", validators[0].OperatorAddress, total)
+	}
+	return nil
+}
+
+func allocationTotal(validators []models.Validator) (uint, bool) {
+	var total uint
+	weighted := false
+	for _, validator := range validators {
+		if validator.AllocationPercentage > 0 {
+			weighted = true
+			total += validator.AllocationPercentage
+		}
+	}
+	return total, weighted
+}
+
+// allocateBalance splits an operator's unboosted balance across its validators, in the same order.
+func allocateBalance(balance *big.Int, validators []models.Validator) ([]*big.Int, error) {
+	if err := CheckAllocation(validators); err != nil {
+		return nil, err
+	}
+	_, weighted := allocationTotal(validators)
+
+	shares := make([]*big.Int, len(validators))
+	for i, validator := range validators {
+		if weighted {
+			shares[i] = new(big.Int).Mul(balance, big.NewInt(int64(validator.AllocationPercentage)))
+			shares[i].Div(shares[i], big.NewInt(100))
+		} else {
+			shares[i] = new(big.Int).Div(balance, big.NewInt(int64(len(validators))))
+		}
+	}
+	return shares, nil
+}
+
+// GroupByOperator groups validators by operator address, keeping the order in which operators first appear.
+func GroupByOperator(validators []models.Validator) [][]models.Validator {
+	var groups [][]models.Validator
+	index := make(map[common.Address]int)
+	for _, validator := range validators {
+		operator := common.HexToAddress(validator.OperatorAddress)
+		i, ok := index[operator]
+		if !ok {
+			i = len(groups)
+			index[operator] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], validator)
+	}
+	return groups
+}
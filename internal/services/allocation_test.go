@@ -0,0 +1,55 @@
+package services
+
+import (
+	"bgt_boost/internal/models"
+	"math/big"
+	"testing"
+)
+
+func TestAllocateBalance(t *testing.T) {
+	const operator = "0x1111111111111111111111111111111111111111"
+	tests := []struct {
+		name        string
+		balance     int64
+		percentages []uint
+		want        []int64
+		wantErr     bool
+	}{
+		{"single validator", 1000, []uint{0}, []int64{1000}, false},
+		{"equal split", 900, []uint{0, 0, 0}, []int64{300, 300, 300}, false},
+		{"equal split rounds down", 100, []uint{0, 0, 0}, []int64{33, 33, 33}, false},
+		{"weighted", 1000, []uint{50, 30, 20}, []int64{500, 300, 200}, false},
+		{"weighted rounds down", 999, []uint{50, 50}, []int64{499, 499}, false},
+		{"zero balance", 0, []uint{60, 40}, []int64{0, 0}, false},
+		{"percentages below 100", 1000, []uint{50, 30}, nil, true},
+		{"percentages above 100", 1000, []uint{80, 30}, nil, true},
+		{"validator without a percentage among weighted ones", 1000, []uint{100, 0}, nil, true},
+		{"validator added without a percentage", 1000, []uint{50, 50, 0}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validators := make([]models.Validator, len(tt.percentages))
+			for i, percentage := range tt.percentages {
+				validators[i] = models.Validator{OperatorAddress: operator, AllocationPercentage: percentage}
+			}
+			got, err := allocateBalance(big.NewInt(tt.balance), validators)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("allocateBalance() = %v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("allocateBalance() unexpected error: %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("allocateBalance() returned %d shares, want %d", len(got), len(tt.want))
+			}
+			for i, share := range got {
+				if share.Cmp(big.NewInt(tt.want[i])) != 0 {
+					t.Errorf("share %d = %s, want %d", i, share, tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -9,13 +9,29 @@ import (
 	"fmt"
 	"log"
 	"math/big"
+	"sync"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type BoostService interface {
-	BoostValidator(ctx context.Context) error
+	BoostValidator(ctx context.Context) (RunReport, error)
+	BoostOperators(ctx context.Context, operatorAddresses []common.Address) (RunReport, error)
+	Plan(ctx context.Context) (Plan, error)
+	PlanOperators(ctx context.Context, operatorAddresses []common.Address) (Plan, error)
+	CancelBoost(ctx context.Context, pubkey string, amount *big.Int) (PendingCancel, error)
+	CancelDropBoost(ctx context.Context, pubkey string, amount *big.Int) (PendingCancel, error)
+	GetOperatorBalances(ctx context.Context, address string) (OperatorBalances, error)
+	LoadActivationSchedule(ctx context.Context) error
+	RunActivationScheduler(ctx context.Context)
+	UpcomingActivations(ctx context.Context) ([]UpcomingActivation, error)
+	Reconcile(ctx context.Context, correct bool) (Reconciliation, error)
+	Backfill(ctx context.Context, fromBlock *uint64, toBlock *uint64) (BackfillReport, error)
+	ResumeTransactions(ctx context.Context) error
+	VerifyTransactions(ctx context.Context) error
 }
 
 type boostService struct {
@@ -23,6 +39,10 @@ type boostService struct {
 	dbRepository  *repository.DbRepository
 	ethRepository *repository.EthRepository
 	signerService *SignerService
+	operatorLocks operatorLocks
+	nonces        nonceManager
+	activations   activationSchedule
+	indexerLock   sync.Mutex
 }
 
 func NewBoostService(config *config.Config, dbRepository *repository.DbRepository, ethRepository *repository.EthRepository, signerService *SignerService) BoostService {
@@ -34,167 +54,315 @@ func NewBoostService(config *config.Config, dbRepository *repository.DbRepositor
 	}
 }
 
-func (s *boostService) BoostValidator(ctx context.Context) error {
+func (s *boostService) BoostValidator(ctx context.Context) (RunReport, error) {
 	validators, err := (*s.dbRepository).GetValidators(ctx)
 	if err != nil {
-		return err
+		return RunReport{StartedAt: time.Now()}, err
 	}
+	return s.boost(ctx, validators)
+}
 
-	log.Printf("Found %d validators", len(validators))
-	for _, validator := range validators {
-		log.Println("Processing validator: ", validator.Pubkey)
-		if err := s.processValidator(ctx, validator); err != nil {
-			return err
-		}
+// BoostOperators runs the boost engine for the validators of the given operators only.
+func (s *boostService) BoostOperators(ctx context.Context, operatorAddresses []common.Address) (RunReport, error) {
+	validators, err := (*s.dbRepository).GetValidators(ctx)
+	if err != nil {
+		return RunReport{StartedAt: time.Now()}, err
 	}
-	return nil
+	return s.boost(ctx, filterByOperator(validators, operatorAddresses))
 }
 
-func (s *boostService) processValidator(ctx context.Context, validator models.Validator) error {
-	if err := s.checkAndQueueBoost(ctx, validator); err != nil {
-		return err
+func (s *boostService) boost(ctx context.Context, validators []models.Validator) (RunReport, error) {
+	report := RunReport{StartedAt: time.Now()}
+	operators, err := s.getOperators(ctx)
+	if err != nil {
+		return report, err
+	}
+	state, err := s.getRunState(ctx)
+	if err != nil {
+		return report, err
 	}
-	return s.checkAndActivateBoost(ctx, validator)
+
+	log.Printf("Found %d validators", len(validators))
+	groups := GroupByOperator(validators)
+	results := make([][]ValidatorReport, len(groups))
+	forEachConcurrently(len(groups), s.config.BoostConcurrency, func(i int) {
+		results[i] = s.processOperator(ctx, operatorFor(operators, groups[i][0].OperatorAddress), groups[i], state)
+	})
+	for _, result := range results {
+		report.Validators = append(report.Validators, result...)
+	}
+	report.FinishedAt = time.Now()
+	return report, nil
 }
 
-func (s *boostService) checkAndQueueBoost(ctx context.Context, validator models.Validator) error {
-	unboostedBalance, err := (*s.ethRepository).GetUnboostedBalance(ctx, common.HexToAddress(validator.OperatorAddress))
+// processOperator splits the operator's unboosted balance across its validators and processes each of them,
+// isolating failures to the validator they happened on.
+func (s *boostService) processOperator(ctx context.Context, operator models.Operator, validators []models.Validator, state runState) []ValidatorReport {
+	reports := make([]ValidatorReport, len(validators))
+	for i, validator := range validators {
+		reports[i] = ValidatorReport{
+			Pubkey:          validator.Pubkey,
+			OperatorAddress: validator.OperatorAddress,
+			Status:          ValidatorStatusSkipped,
+		}
+	}
+	if !operator.Enabled {
+		log.Printf("Skipping disabled operator %s", operator.Address)
+		for i := range reports {
+			reports[i].Status = ValidatorStatusDisabled
+		}
+		return reports
+	}
+
+	unlock := s.operatorLocks.lock(operator.Address)
+	defer unlock()
+	log.Printf("Processing operator %s with %d validators", operator.Address, len(validators))
+	// Plans are made from what BGT shows, which an unfinished transaction would still change
+	if err := s.resumeOperatorTransactions(ctx, operator); err != nil {
+		log.Printf("Failed to resume transactions of operator %s: %v", operator.Address, err)
+		for i := range reports {
+			reports[i].fail(err)
+		}
+		return reports
+	}
+	shares, err := s.allocateUnboostedBalance(ctx, operator, validators)
 	if err != nil {
-		return err
+		log.Printf("Failed to allocate unboosted balance of operator %s: %v", operator.Address, err)
+		for i := range reports {
+			reports[i].fail(err)
+		}
+		return reports
+	}
+
+	reportByPubkey := make(map[string]*ValidatorReport, len(reports))
+	for i := range reports {
+		reportByPubkey[reports[i].Pubkey] = &reports[i]
+	}
+
+	var actions []action
+	for i, validator := range validators {
+		log.Println("Processing validator: ", validator.Pubkey)
+		d, err := s.planValidator(ctx, operator, validator, shares[i], state)
+		if err != nil {
+			log.Printf("Failed to process validator %s: %v", validator.Pubkey, err)
+			reports[i].fail(err)
+			continue
+		}
+		s.scheduleActivation(ctx, validator, d.boostedQueue, state.ActivateBoostDelay)
+		reports[i].AtMaxBoost = d.AtMaxBoost
+		reports[i].QueueReset = d.QueueReset
+		reports[i].addDeferred(d.Deferred)
+		actions = append(actions, d.Actions...)
 	}
+
+	s.executeActions(ctx, operator, actions, reportByPubkey)
+	return reports
+}
+
+func (s *boostService) planQueueBoost(ctx context.Context, validator models.Validator, share *big.Int, boostedQueue repository.BoostedQueue, d *decision) (*action, error) {
 	log.Println("Checking queue boost condition")
-	log.Printf("Unboosted balance: %s", unboostedBalance.String())
+	log.Printf("Allocated share: %s", share.String())
 	log.Printf("Boost threshold: %s", validator.BoostThreshold)
 	boostThreshold, ok := big.NewInt(0).SetString(validator.BoostThreshold, 10)
 	if !ok {
-		return errors.New("invalid boostThreshold")
+		return nil, errors.New("invalid boostThreshold")
 	}
-	if unboostedBalance.Cmp(boostThreshold) > 0 {
-		log.Printf("Queueing boost: %s", unboostedBalance.String())
-		transactionInfo, err := s.queueBoost(ctx, validator.OperatorAddress, validator.Pubkey, unboostedBalance)
+	if share.Cmp(boostThreshold) <= 0 {
+		log.Printf("Queue boost condition not met")
+		return nil, nil
+	}
+
+	amount := share
+	if validator.MaxBoost != "" {
+		room, err := s.remainingBoost(ctx, validator, boostedQueue)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		log.Printf("Queued boost: %s", transactionInfo.TransactionHash)
-		return s.recordQueueBoost(ctx, validator, unboostedBalance, transactionInfo)
+		log.Printf("Remaining boost before max boost: %s", room.String())
+		if room.Sign() <= 0 {
+			log.Printf("Validator is at max boost")
+			d.AtMaxBoost = true
+			return nil, nil
+		}
+		if room.Cmp(amount) < 0 {
+			amount = room
+		}
+	}
+
+	a, err := s.newAction(methodQueueBoost, validator, amount, common.FromHex(validator.Pubkey), amount)
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// remainingBoost is how much more can be queued before the validator's boost, including the amount still pending
+// in its boostedQueue, reaches its MaxBoost.
+func (s *boostService) remainingBoost(ctx context.Context, validator models.Validator, boostedQueue repository.BoostedQueue) (*big.Int, error) {
+	maxBoost, ok := big.NewInt(0).SetString(validator.MaxBoost, 10)
+	if !ok {
+		return nil, errors.New("invalid maxBoost")
+	}
+	boosted, err := (*s.ethRepository).GetBoosted(ctx, common.HexToAddress(validator.OperatorAddress), validator.Pubkey)
+	if err != nil {
+		return nil, err
 	}
-	log.Printf("Queue boost condition not met")
-	return nil
+	log.Printf("Boosted: %s", boosted.String())
+	room := new(big.Int).Sub(maxBoost, boosted)
+	return room.Sub(room, boostedQueue.Balance), nil
 }
 
+// recordQueueBoost records the new queue. Queue records still waiting for activation are superseded, since the
+// queueBoost restarted the activation delay of the whole boostedQueue.
 func (s *boostService) recordQueueBoost(ctx context.Context, validator models.Validator, amount *big.Int, transactionInfo repository.TransactionInfo) error {
+	exists, err := (*s.dbRepository).DoesQueueBoostExist(ctx, validator.Pubkey)
+	if err != nil {
+		return err
+	}
+	if exists {
+		if err := (*s.dbRepository).MarkQueueBoostsSuperseded(ctx, validator.Pubkey, transactionInfo.TransactionHash, transactionInfo.BlockNumber); err != nil {
+			return err
+		}
+	}
 	return (*s.dbRepository).AddQueueBoost(ctx, models.QueueBoost{
 		ValidatorPubkey: validator.Pubkey,
 		OperatorAddress: validator.OperatorAddress,
 		Amount:          amount.String(),
 		TransactionHash: transactionInfo.TransactionHash,
 		BlockNumber:     transactionInfo.BlockNumber,
+		BlockHash:       transactionInfo.BlockHash,
 		BlockTimestamp:  transactionInfo.BlockTimestamp,
 		Fee:             transactionInfo.TransactionFee,
+		Fees:            transactionFees(transactionInfo),
 		TransactionFrom: validator.OperatorAddress,
 		ToContract:      s.config.BGTContract.Address.Hex(),
+		BatchSize:       transactionInfo.BatchSize,
+		Status:          models.QueueBoostStatusPending,
 	})
 }
 
-func (s *boostService) checkAndActivateBoost(ctx context.Context, validator models.Validator) error {
+func (s *boostService) planActivateBoost(validator models.Validator, boostedQueue repository.BoostedQueue, state runState) (*action, error) {
 	log.Println("Checking activate boost condition")
-	boostedQueue, err := (*s.ethRepository).GetBoostedQueue(ctx, common.HexToAddress(validator.OperatorAddress), validator.Pubkey)
-	if err != nil {
-		return err
-	}
 	log.Printf("Boosted queue balance: %s", boostedQueue.Balance.String())
 	log.Printf("Boosted queue block number: %d", boostedQueue.BlockNumber)
+	log.Printf("Activation delay: %d", state.ActivateBoostDelay)
+	log.Printf("Current block: %d", state.CurrentBlock)
 
-	currentBlock, err := (*s.ethRepository).GetLatestBlock(ctx)
-	if err != nil {
-		return err
-	}
-	activationDelay, err := (*s.ethRepository).GetActivateBoostDelay(ctx)
-	if err != nil {
-		return err
-	}
-	log.Printf("Activation delay: %d", activationDelay)
-	log.Printf("Current block: %d", currentBlock)
-
-	if boostedQueue.Balance.Cmp(big.NewInt(0)) > 0 && currentBlock > boostedQueue.BlockNumber+activationDelay {
-		log.Printf("Activating boost: %s", boostedQueue.Balance.String())
-		transactionInfo, err := s.activateBoost(ctx, validator.OperatorAddress, validator.Pubkey)
+	if boostedQueue.Balance.Cmp(big.NewInt(0)) > 0 && state.CurrentBlock > boostedQueue.BlockNumber+state.ActivateBoostDelay {
+		a, err := s.newAction(methodActivateBoost, validator, boostedQueue.Balance, common.HexToAddress(validator.OperatorAddress), common.FromHex(validator.Pubkey))
 		if err != nil {
-			return err
+			return nil, err
 		}
-		log.Printf("Activated boost: %s", transactionInfo.TransactionHash)
-		return s.recordActivateBoost(ctx, validator, boostedQueue, transactionInfo)
+		return &a, nil
 	}
 	log.Printf("Activate boost condition not met")
-	return nil
+	return nil, nil
 }
 
-func (s *boostService) recordActivateBoost(ctx context.Context, validator models.Validator, boostedQueue repository.BoostedQueue, transactionInfo repository.TransactionInfo) error {
-	return (*s.dbRepository).AddActivateBoost(ctx, models.ActivateBoost{
-		Amount:          boostedQueue.Balance.String(),
+// recordActivateBoost records the activation along with the queue records it settled, which are marked as activated.
+func (s *boostService) recordActivateBoost(ctx context.Context, validator models.Validator, amount *big.Int, transactionInfo repository.TransactionInfo) error {
+	queueBoosts, err := (*s.dbRepository).GetInActiveBoosts(ctx, validator.Pubkey, transactionInfo.BlockNumber)
+	if err != nil {
+		return err
+	}
+	queueBoostIDs := make([]primitive.ObjectID, len(queueBoosts))
+	for i, queueBoost := range queueBoosts {
+		queueBoostIDs[i] = queueBoost.ID
+	}
+	err = (*s.dbRepository).AddActivateBoost(ctx, models.ActivateBoost{
+		Amount:          amount.String(),
 		ValidatorPubkey: validator.Pubkey,
 		OperatorAddress: validator.OperatorAddress,
 		TransactionHash: transactionInfo.TransactionHash,
 		BlockNumber:     transactionInfo.BlockNumber,
+		BlockHash:       transactionInfo.BlockHash,
 		BlockTimestamp:  transactionInfo.BlockTimestamp,
 		Fee:             transactionInfo.TransactionFee,
+		Fees:            transactionFees(transactionInfo),
 		TransactionFrom: validator.OperatorAddress,
 		ToContract:      s.config.BGTContract.Address.Hex(),
+		BatchSize:       transactionInfo.BatchSize,
+		QueueBoostIDs:   queueBoostIDs,
 	})
+	if err != nil {
+		return err
+	}
+	return (*s.dbRepository).MarkBoostsAsActivated(ctx, queueBoostIDs, transactionInfo.TransactionHash, transactionInfo.BlockNumber)
 }
 
-func (s *boostService) queueBoost(ctx context.Context, operatorAddress string, pubkey string, amount *big.Int) (repository.TransactionInfo, error) {
-	data, err := s.config.BGTContract.ABI.Pack("queueBoost", common.FromHex(pubkey), amount)
+// send signs a call to the BGT contract with the operator's signer key and waits for it to be mined. The transaction
+// is journaled in the transactions collection, with the actions it makes, before it is signed.
+func (s *boostService) send(ctx context.Context, operator models.Operator, data []byte, actions []action) (repository.TransactionInfo, error) {
+	pending, err := s.broadcast(ctx, operator, data, actions)
 	if err != nil {
-		return repository.TransactionInfo{}, fmt.Errorf("failed to pack data: %w", err)
+		return repository.TransactionInfo{}, err
 	}
-	tx, err := (*s.ethRepository).CreateTransaction(ctx, common.HexToAddress(operatorAddress), s.config.BGTContract.Address, data)
+	return s.finishTransaction(ctx, operator, pending)
+}
+
+// broadcast journals, signs and broadcasts the transaction without waiting for it to be mined.
+func (s *boostService) broadcast(ctx context.Context, operator models.Operator, data []byte, actions []action) (*pendingTransaction, error) {
+	from := common.HexToAddress(operator.Address)
+	fees, err := s.suggestFees(ctx)
 	if err != nil {
-		return repository.TransactionInfo{}, fmt.Errorf("failed to create transaction: %w", err)
+		return nil, fmt.Errorf("failed to price transaction: %w", err)
 	}
-	signedTx, err := (*s.signerService).SignTransaction(ctx, operatorAddress, tx)
+	nonce, err := s.reserveNonce(ctx, from)
 	if err != nil {
-		return repository.TransactionInfo{}, fmt.Errorf("failed to sign transaction: %w", err)
+		return nil, err
 	}
-
-	// Convert hex string back to transaction
-	signedTxBytes := common.FromHex(signedTx)
-	decodedTx := new(types.Transaction)
-	if err := decodedTx.UnmarshalBinary(signedTxBytes); err != nil {
-		return repository.TransactionInfo{}, fmt.Errorf("failed to decode signed transaction: %w", err)
-	}
-
-	txInfo, err := (*s.ethRepository).SendTransaction(ctx, decodedTx)
+	tx, err := (*s.ethRepository).CreateTransaction(ctx, from, s.config.BGTContract.Address, data, nonce, fees)
 	if err != nil {
-		return repository.TransactionInfo{}, fmt.Errorf("failed to send transaction: %w", err)
+		s.unreserveNonce(ctx, from, nonce)
+		return nil, fmt.Errorf("failed to create transaction: %w", err)
 	}
-	return txInfo, nil
-}
-
-func (s *boostService) activateBoost(ctx context.Context, operatorAddress string, pubkey string) (repository.TransactionInfo, error) {
-	data, err := s.config.BGTContract.ABI.Pack("activateBoost", common.HexToAddress(operatorAddress), common.FromHex(pubkey))
+	journal, err := s.journalTransaction(ctx, from, tx, transactionIntent(actions), primitive.NilObjectID)
 	if err != nil {
-		return repository.TransactionInfo{}, fmt.Errorf("failed to pack data: %w", err)
+		s.unreserveNonce(ctx, from, nonce)
+		return nil, err
 	}
-	tx, err := (*s.ethRepository).CreateTransaction(ctx, common.HexToAddress(operatorAddress), s.config.BGTContract.Address, data)
+	signedTx, err := s.signJournaled(ctx, operator, &journal, tx)
 	if err != nil {
-		return repository.TransactionInfo{}, fmt.Errorf("failed to create transaction: %w", err)
+		s.releaseNonce(ctx, from, nonce)
+		s.nonces.invalidate(from)
+		return nil, err
+	}
+	s.trackNonce(ctx, from, nonce, signedTx.Hash())
+
+	if err := (*s.ethRepository).BroadcastTransaction(ctx, signedTx); err != nil {
+		// The transaction may still have reached the pool and be mined, so it stays signed to be broadcast again or
+		// found when it is resumed, and its nonce stays in flight for the next sync to decide
+		s.markJournaled(ctx, journal.ID, models.TransactionStateSigned, err)
+		s.nonces.invalidate(from)
+		return nil, err
 	}
-	signedTx, err := (*s.signerService).SignTransaction(ctx, operatorAddress, tx)
+	s.markJournaled(ctx, journal.ID, models.TransactionStateBroadcast, nil)
+	return newPendingTransaction(journal, signedTx), nil
+}
+
+// signTransaction signs the transaction with the operator's signer key, which Web3Signer looks up by the operator
+// address, and checks that the signature really is the operator's.
+func (s *boostService) signTransaction(ctx context.Context, operator models.Operator, tx *types.Transaction) (*types.Transaction, error) {
+	if err := CheckSignerKey(operator); err != nil {
+		return nil, err
+	}
+	signedTx, err := (*s.signerService).SignTransaction(ctx, operator.Address, tx)
 	if err != nil {
-		return repository.TransactionInfo{}, fmt.Errorf("failed to sign transaction: %w", err)
+		return nil, fmt.Errorf("failed to sign transaction: %w", err)
 	}
 
 	// Convert hex string back to transaction
 	signedTxBytes := common.FromHex(signedTx)
 	decodedTx := new(types.Transaction)
 	if err := decodedTx.UnmarshalBinary(signedTxBytes); err != nil {
-		return repository.TransactionInfo{}, fmt.Errorf("failed to decode signed transaction: %w", err)
+		return nil, fmt.Errorf("failed to decode signed transaction: %w", err)
 	}
-
-	txInfo, err := (*s.ethRepository).SendTransaction(ctx, decodedTx)
+	sender, err := types.Sender(types.LatestSignerForChainID(decodedTx.ChainId()), decodedTx)
 	if err != nil {
-		return repository.TransactionInfo{}, fmt.Errorf("failed to send transaction: %w", err)
+		return nil, fmt.Errorf("failed to recover signer: %w", err)
+	}
+	if sender != common.HexToAddress(operator.Address) {
+		return nil, fmt.Errorf("%w: transaction was signed by %s", ErrSignerKeyMismatch, sender.Hex())
 	}
-	return txInfo, nil
+	return decodedTx, nil
 }
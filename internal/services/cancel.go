@@ -0,0 +1,238 @@
+package services
+
+import (
+	"bgt_boost/internal/models"
+	"bgt_boost/internal/repository"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var (
+	ErrNothingToCancel       = errors.New("nothing is queued for this validator")
+	ErrCancelAmountTooHigh   = errors.New("amount is greater than the queued balance")
+	ErrInvalidCancelAmount   = errors.New("amount should be greater than 0")
+	ErrValidatorDoesNotExist = errors.New("validator does not exist")
+)
+
+// PendingCancel is a cancel transaction that was broadcast and is recorded once it is mined.
+type PendingCancel struct {
+	TransactionHash string `json:"transactionHash"`
+	Amount          string `json:"amount"`
+}
+
+// CancelBoost cancels amount of the validator's pending boost queue, or all of it when amount is nil. It returns once
+// the transaction is broadcast and records the cancel in the background.
+func (s *boostService) CancelBoost(ctx context.Context, pubkey string, amount *big.Int) (PendingCancel, error) {
+	validator, err := s.getValidator(ctx, pubkey)
+	if err != nil {
+		return PendingCancel{}, err
+	}
+	operator, err := s.getOperator(ctx, validator.OperatorAddress)
+	if err != nil {
+		return PendingCancel{}, err
+	}
+	unlock := s.operatorLocks.lock(validator.OperatorAddress)
+	boostedQueue, err := (*s.ethRepository).GetBoostedQueue(ctx, common.HexToAddress(validator.OperatorAddress), validator.Pubkey)
+	if err != nil {
+		unlock()
+		return PendingCancel{}, err
+	}
+	amount, err = cancelAmount(boostedQueue, amount)
+	if err != nil {
+		unlock()
+		return PendingCancel{}, err
+	}
+	a, err := s.newAction(methodCancelBoost, validator, amount, common.FromHex(validator.Pubkey), amount)
+	if err != nil {
+		unlock()
+		return PendingCancel{}, err
+	}
+	a.cancelsQueue = amount.Cmp(boostedQueue.Balance) == 0
+
+	log.Printf("Cancelling boost for validator %s: %s", validator.Pubkey, amount.String())
+	return s.sendCancel(ctx, operator, a, unlock, func(transactionInfo repository.TransactionInfo) error {
+		log.Printf("Cancelled boost: %s", transactionInfo.TransactionHash)
+		s.refreshActivation(ctx, validator)
+		_, err := s.recordCancelBoost(ctx, validator, amount, a.cancelsQueue, transactionInfo)
+		return err
+	})
+}
+
+// sendCancel broadcasts the cancel and leaves waiting for it and recording it to a goroutine, which keeps the
+// operator's lock until then and releases it with unlock.
+func (s *boostService) sendCancel(ctx context.Context, operator models.Operator, a action, unlock func(), record func(repository.TransactionInfo) error) (PendingCancel, error) {
+	pending, err := s.broadcast(ctx, operator, a.Data, []action{a})
+	if err != nil {
+		unlock()
+		return PendingCancel{}, err
+	}
+	go func() {
+		defer unlock()
+		transactionInfo, err := s.finishTransaction(ctx, operator, pending)
+		if err == nil {
+			err = record(transactionInfo)
+		}
+		if err != nil {
+			log.Printf("Failed to cancel %s for validator %s: %v", a.Amount.String(), a.Validator.Pubkey, err)
+			return
+		}
+		s.markTransactionRecorded(ctx, transactionInfo)
+	}()
+	return PendingCancel{TransactionHash: pending.signedTx.Hash().Hex(), Amount: a.Amount.String()}, nil
+}
+
+func (s *boostService) recordCancelBoost(ctx context.Context, validator models.Validator, amount *big.Int, cancelsQueue bool, transactionInfo repository.TransactionInfo) (models.CancelBoost, error) {
+	cancelBoost := models.CancelBoost{
+		Amount:          amount.String(),
+		ValidatorPubkey: validator.Pubkey,
+		OperatorAddress: validator.OperatorAddress,
+		TransactionHash: transactionInfo.TransactionHash,
+		BlockNumber:     transactionInfo.BlockNumber,
+		BlockTimestamp:  transactionInfo.BlockTimestamp,
+		Fee:             transactionInfo.TransactionFee,
+		Fees:            transactionFees(transactionInfo),
+		TransactionFrom: validator.OperatorAddress,
+		ToContract:      s.config.BGTContract.Address.Hex(),
+	}
+	if err := (*s.dbRepository).AddCancelBoost(ctx, cancelBoost); err != nil {
+		return cancelBoost, err
+	}
+	if cancelsQueue {
+		return cancelBoost, (*s.dbRepository).MarkQueueBoostsCancelled(ctx, validator.Pubkey, transactionInfo.TransactionHash)
+	}
+	return cancelBoost, s.cancelQueuedAmount(ctx, validator, amount, transactionInfo)
+}
+
+// cancelQueuedAmount takes a partial cancel out of the validator's waiting queue records, latest first, so that what
+// they still hold adds up to the boostedQueue balance. Records emptied by the cancel are marked cancelled.
+func (s *boostService) cancelQueuedAmount(ctx context.Context, validator models.Validator, amount *big.Int, transactionInfo repository.TransactionInfo) error {
+	queueBoosts, err := (*s.dbRepository).GetInActiveBoosts(ctx, validator.Pubkey, transactionInfo.BlockNumber)
+	if err != nil {
+		return err
+	}
+	left := new(big.Int).Set(amount)
+	for i := len(queueBoosts) - 1; i >= 0 && left.Sign() > 0; i-- {
+		queueBoost := queueBoosts[i]
+		queued, ok := new(big.Int).SetString(queueBoost.Amount, 10)
+		if !ok {
+			return fmt.Errorf("invalid queue boost amount: %s", queueBoost.Amount)
+		}
+		cancelled := big.NewInt(0)
+		if queueBoost.CancelledAmount != "" {
+			if _, ok := cancelled.SetString(queueBoost.CancelledAmount, 10); !ok {
+				return fmt.Errorf("invalid queue boost cancelled amount: %s", queueBoost.CancelledAmount)
+			}
+		}
+		queued.Sub(queued, cancelled)
+		if queued.Sign() <= 0 {
+			continue
+		}
+
+		taken := queued
+		if left.Cmp(queued) < 0 {
+			taken = left
+		}
+		status := queueBoost.Status
+		if taken.Cmp(queued) == 0 {
+			status = models.QueueBoostStatusCancelled
+		}
+		cancelled.Add(cancelled, taken)
+		if err := (*s.dbRepository).MarkQueueBoostCancelledAmount(ctx, queueBoost.ID, cancelled.String(), status, transactionInfo.TransactionHash); err != nil {
+			return err
+		}
+		left = new(big.Int).Sub(left, taken)
+	}
+	return nil
+}
+
+// CancelDropBoost cancels amount of the validator's pending drop boost queue, or all of it when amount is nil. Like
+// CancelBoost, it returns once the transaction is broadcast.
+func (s *boostService) CancelDropBoost(ctx context.Context, pubkey string, amount *big.Int) (PendingCancel, error) {
+	validator, err := s.getValidator(ctx, pubkey)
+	if err != nil {
+		return PendingCancel{}, err
+	}
+	operator, err := s.getOperator(ctx, validator.OperatorAddress)
+	if err != nil {
+		return PendingCancel{}, err
+	}
+	unlock := s.operatorLocks.lock(validator.OperatorAddress)
+	dropBoostQueue, err := (*s.ethRepository).GetDropBoostQueue(ctx, common.HexToAddress(validator.OperatorAddress), validator.Pubkey)
+	if err != nil {
+		unlock()
+		return PendingCancel{}, err
+	}
+	amount, err = cancelAmount(dropBoostQueue, amount)
+	if err != nil {
+		unlock()
+		return PendingCancel{}, err
+	}
+	a, err := s.newAction(methodCancelDropBoost, validator, amount, common.FromHex(validator.Pubkey), amount)
+	if err != nil {
+		unlock()
+		return PendingCancel{}, err
+	}
+	a.cancelsQueue = amount.Cmp(dropBoostQueue.Balance) == 0
+
+	log.Printf("Cancelling drop boost for validator %s: %s", validator.Pubkey, amount.String())
+	return s.sendCancel(ctx, operator, a, unlock, func(transactionInfo repository.TransactionInfo) error {
+		log.Printf("Cancelled drop boost: %s", transactionInfo.TransactionHash)
+		_, err := s.recordCancelDropBoost(ctx, validator, amount, a.cancelsQueue, transactionInfo)
+		return err
+	})
+}
+
+func (s *boostService) recordCancelDropBoost(ctx context.Context, validator models.Validator, amount *big.Int, cancelsQueue bool, transactionInfo repository.TransactionInfo) (models.CancelDropBoost, error) {
+	cancelDropBoost := models.CancelDropBoost{
+		Amount:          amount.String(),
+		ValidatorPubkey: validator.Pubkey,
+		OperatorAddress: validator.OperatorAddress,
+		TransactionHash: transactionInfo.TransactionHash,
+		BlockNumber:     transactionInfo.BlockNumber,
+		BlockTimestamp:  transactionInfo.BlockTimestamp,
+		Fee:             transactionInfo.TransactionFee,
+		Fees:            transactionFees(transactionInfo),
+		TransactionFrom: validator.OperatorAddress,
+		ToContract:      s.config.BGTContract.Address.Hex(),
+	}
+	if err := (*s.dbRepository).AddCancelDropBoost(ctx, cancelDropBoost); err != nil {
+		return cancelDropBoost, err
+	}
+	if cancelsQueue {
+		return cancelDropBoost, (*s.dbRepository).MarkQueueDropBoostsCancelled(ctx, validator.Pubkey, transactionInfo.TransactionHash)
+	}
+	return cancelDropBoost, nil
+}
+
+func (s *boostService) getValidator(ctx context.Context, pubkey string) (models.Validator, error) {
+	exists, err := (*s.dbRepository).DoesValidatorExist(ctx, pubkey)
+	if err != nil {
+		return models.Validator{}, err
+	}
+	if !exists {
+		return models.Validator{}, ErrValidatorDoesNotExist
+	}
+	return (*s.dbRepository).GetValidator(ctx, pubkey)
+}
+
+// cancelAmount defaults amount to the whole queue balance and checks it can be cancelled.
+func cancelAmount(queue repository.BoostedQueue, amount *big.Int) (*big.Int, error) {
+	if queue.Balance.Sign() <= 0 {
+		return nil, ErrNothingToCancel
+	}
+	if amount == nil {
+		return queue.Balance, nil
+	}
+	if amount.Sign() <= 0 {
+		return nil, ErrInvalidCancelAmount
+	}
+	if amount.Cmp(queue.Balance) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrCancelAmountTooHigh, queue.Balance.String())
+	}
+	return amount, nil
+}
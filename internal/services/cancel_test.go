@@ -0,0 +1,52 @@
+package services
+
+import (
+	"bgt_boost/internal/repository"
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func queueOf(balance int64) repository.BoostedQueue {
+	return repository.BoostedQueue{Balance: big.NewInt(balance), BlockNumber: 100}
+}
+
+func TestCancelAmountDefaultsToQueuedBalance(t *testing.T) {
+	amount, err := cancelAmount(queueOf(500), nil)
+	if err != nil {
+		t.Fatalf("cancelAmount() unexpected error: %v", err)
+	}
+	if amount.Cmp(big.NewInt(500)) != 0 {
+		t.Errorf("cancelAmount() = %s, want the queued 500", amount)
+	}
+}
+
+func TestCancelAmountKeepsRequestedAmount(t *testing.T) {
+	for _, requested := range []int64{1, 200, 500} {
+		amount, err := cancelAmount(queueOf(500), big.NewInt(requested))
+		if err != nil {
+			t.Fatalf("cancelAmount(%d) unexpected error: %v", requested, err)
+		}
+		if amount.Cmp(big.NewInt(requested)) != 0 {
+			t.Errorf("cancelAmount(%d) = %s", requested, amount)
+		}
+	}
+}
+
+func TestCancelAmountRejects(t *testing.T) {
+	if _, err := cancelAmount(queueOf(0), nil); !errors.Is(err, ErrNothingToCancel) {
+		t.Errorf("empty queue: got %v, want %v", err, ErrNothingToCancel)
+	}
+	if _, err := cancelAmount(queueOf(0), big.NewInt(1)); !errors.Is(err, ErrNothingToCancel) {
+		t.Errorf("empty queue with an amount: got %v, want %v", err, ErrNothingToCancel)
+	}
+	if _, err := cancelAmount(queueOf(500), big.NewInt(0)); !errors.Is(err, ErrInvalidCancelAmount) {
+		t.Errorf("zero amount: got %v, want %v", err, ErrInvalidCancelAmount)
+	}
+	if _, err := cancelAmount(queueOf(500), big.NewInt(-1)); !errors.Is(err, ErrInvalidCancelAmount) {
+		t.Errorf("negative amount: got %v, want %v", err, ErrInvalidCancelAmount)
+	}
+	if _, err := cancelAmount(queueOf(500), big.NewInt(501)); !errors.Is(err, ErrCancelAmountTooHigh) {
+		t.Errorf("more than queued: got %v, want %v", err, ErrCancelAmountTooHigh)
+	}
+}
@@ -0,0 +1,149 @@
+package services
+
+import (
+	"bgt_boost/internal/models"
+	"bgt_boost/internal/repository"
+	"context"
+	"fmt"
+	"log"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func (s *boostService) planQueueDropBoost(ctx context.Context, validator models.Validator) (*action, error) {
+	requests, err := (*s.dbRepository).GetDropBoostRequests(ctx, validator.Pubkey, models.DropBoostRequestStatusPending)
+	if err != nil {
+		return nil, err
+	}
+	if len(requests) == 0 {
+		return nil, nil
+	}
+	// A queueDropBoost whose records failed to be written leaves its requests pending until it is resumed
+	unrecorded, err := (*s.dbRepository).GetUnrecordedDropBoostRequestIDs(ctx, validator.Pubkey)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Println("Checking queue drop boost condition")
+	amount := big.NewInt(0)
+	ids := make([]primitive.ObjectID, 0, len(requests))
+	for _, request := range requests {
+		if containsID(unrecorded, request.ID) {
+			log.Printf("Drop boost request %s is already being queued", request.ID.Hex())
+			continue
+		}
+		requestAmount, ok := big.NewInt(0).SetString(request.Amount, 10)
+		if !ok {
+			return nil, fmt.Errorf("invalid drop boost request amount: %s", request.Amount)
+		}
+		amount.Add(amount, requestAmount)
+		ids = append(ids, request.ID)
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	log.Printf("Requested drop boost: %s", amount.String())
+
+	a, err := s.newAction(methodQueueDropBoost, validator, amount, common.FromHex(validator.Pubkey), amount)
+	if err != nil {
+		return nil, err
+	}
+	a.dropBoostRequestIDs = ids
+	return &a, nil
+}
+
+func (s *boostService) recordQueueDropBoost(ctx context.Context, validator models.Validator, amount *big.Int, transactionInfo repository.TransactionInfo) error {
+	return (*s.dbRepository).AddQueueDropBoost(ctx, models.QueueDropBoost{
+		ValidatorPubkey: validator.Pubkey,
+		OperatorAddress: validator.OperatorAddress,
+		Amount:          amount.String(),
+		TransactionHash: transactionInfo.TransactionHash,
+		BlockNumber:     transactionInfo.BlockNumber,
+		BlockTimestamp:  transactionInfo.BlockTimestamp,
+		Fee:             transactionInfo.TransactionFee,
+		Fees:            transactionFees(transactionInfo),
+		TransactionFrom: validator.OperatorAddress,
+		ToContract:      s.config.BGTContract.Address.Hex(),
+		BatchSize:       transactionInfo.BatchSize,
+	})
+}
+
+func (s *boostService) planDropBoost(ctx context.Context, validator models.Validator, state runState) (*action, error) {
+	dropBoostQueue, err := (*s.ethRepository).GetDropBoostQueue(ctx, common.HexToAddress(validator.OperatorAddress), validator.Pubkey)
+	if err != nil {
+		return nil, err
+	}
+	if dropBoostQueue.Balance.Cmp(big.NewInt(0)) <= 0 {
+		return nil, nil
+	}
+	log.Println("Checking drop boost condition")
+	log.Printf("Drop boost queue balance: %s", dropBoostQueue.Balance.String())
+	log.Printf("Drop boost queue block number: %d", dropBoostQueue.BlockNumber)
+	log.Printf("Drop delay: %d", state.DropBoostDelay)
+	log.Printf("Current block: %d", state.CurrentBlock)
+
+	if state.CurrentBlock > dropBoostQueue.BlockNumber+state.DropBoostDelay {
+		a, err := s.newAction(methodDropBoost, validator, dropBoostQueue.Balance, common.HexToAddress(validator.OperatorAddress), common.FromHex(validator.Pubkey))
+		if err != nil {
+			return nil, err
+		}
+		a.dropBoostRequestIDs, err = s.coveredRequests(ctx, validator, models.DropBoostRequestStatusQueued, dropBoostQueue.Balance)
+		if err != nil {
+			return nil, err
+		}
+		return &a, nil
+	}
+	log.Printf("Drop boost condition not met")
+	return nil, nil
+}
+
+// coveredRequests returns the drop boost requests with status, oldest first, that a queueDropBoost or dropBoost of
+// amount settles. Requests the amount does not fully cover, such as ones queued after a manual queueDropBoost, keep
+// their status.
+func (s *boostService) coveredRequests(ctx context.Context, validator models.Validator, status string, amount *big.Int) ([]primitive.ObjectID, error) {
+	requests, err := (*s.dbRepository).GetDropBoostRequests(ctx, validator.Pubkey, status)
+	if err != nil {
+		return nil, err
+	}
+	var ids []primitive.ObjectID
+	remaining := new(big.Int).Set(amount)
+	for _, request := range requests {
+		requestAmount, ok := big.NewInt(0).SetString(request.Amount, 10)
+		if !ok {
+			return nil, fmt.Errorf("invalid drop boost request amount: %s", request.Amount)
+		}
+		if requestAmount.Cmp(remaining) > 0 {
+			break
+		}
+		remaining.Sub(remaining, requestAmount)
+		ids = append(ids, request.ID)
+	}
+	return ids, nil
+}
+
+func containsID(ids []primitive.ObjectID, id primitive.ObjectID) bool {
+	for _, candidate := range ids {
+		if candidate == id {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *boostService) recordDropBoost(ctx context.Context, validator models.Validator, amount *big.Int, transactionInfo repository.TransactionInfo) error {
+	return (*s.dbRepository).AddDropBoost(ctx, models.DropBoost{
+		Amount:          amount.String(),
+		ValidatorPubkey: validator.Pubkey,
+		OperatorAddress: validator.OperatorAddress,
+		TransactionHash: transactionInfo.TransactionHash,
+		BlockNumber:     transactionInfo.BlockNumber,
+		BlockTimestamp:  transactionInfo.BlockTimestamp,
+		Fee:             transactionInfo.TransactionFee,
+		Fees:            transactionFees(transactionInfo),
+		TransactionFrom: validator.OperatorAddress,
+		ToContract:      s.config.BGTContract.Address.Hex(),
+		BatchSize:       transactionInfo.BatchSize,
+	})
+}
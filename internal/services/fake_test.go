@@ -0,0 +1,190 @@
+package services
+
+import (
+	"bgt_boost/internal/config"
+	"bgt_boost/internal/models"
+	"bgt_boost/internal/repository"
+	"context"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// fakeEth serves the chain state the tests set up. Methods a test does not expect panic on the nil embedded
+// interface.
+type fakeEth struct {
+	repository.EthRepository
+	latestBlock uint64
+	confirmed   uint64
+	pending     uint64
+	// known are the transactions some endpoint has, mined or pooled
+	known map[common.Hash]bool
+	// inclusions are the canonical blocks of mined transactions
+	inclusions map[common.Hash]*repository.TransactionInclusion
+	// dropped are the transactions every caught-up endpoint misses
+	dropped     map[common.Hash]bool
+	broadcast   []common.Hash
+	baseFee     *big.Int
+	tip         *big.Int
+	waits       int
+	waitResults []waitResult
+}
+
+type waitResult struct {
+	info repository.TransactionInfo
+	err  error
+}
+
+func (e *fakeEth) GetLatestBlock(ctx context.Context) (uint64, error) {
+	return e.latestBlock, nil
+}
+
+func (e *fakeEth) GetNonces(ctx context.Context, address common.Address) (uint64, uint64, error) {
+	return e.confirmed, e.pending, nil
+}
+
+func (e *fakeEth) IsTransactionKnown(ctx context.Context, transactionHash common.Hash) (bool, error) {
+	return e.known[transactionHash], nil
+}
+
+func (e *fakeEth) GetTransactionInclusion(ctx context.Context, transactionHash common.Hash) (*repository.TransactionInclusion, error) {
+	return e.inclusions[transactionHash], nil
+}
+
+func (e *fakeEth) IsTransactionDropped(ctx context.Context, transactionHash common.Hash, blockNumber uint64) (bool, error) {
+	return e.dropped[transactionHash], nil
+}
+
+func (e *fakeEth) BroadcastTransaction(ctx context.Context, signedTx *types.Transaction) error {
+	e.broadcast = append(e.broadcast, signedTx.Hash())
+	return nil
+}
+
+func (e *fakeEth) GetBaseFee(ctx context.Context) (*big.Int, error) {
+	return e.baseFee, nil
+}
+
+func (e *fakeEth) SuggestGasTipCap(ctx context.Context) (*big.Int, error) {
+	return e.tip, nil
+}
+
+// WaitForTransaction returns the next of waitResults, and times out once they run out.
+func (e *fakeEth) WaitForTransaction(ctx context.Context, transactionHashes []common.Hash, timeoutBlocks uint64) (repository.TransactionInfo, error) {
+	e.waits++
+	if len(e.waitResults) == 0 {
+		return repository.TransactionInfo{}, repository.ErrInclusionTimeout
+	}
+	result := e.waitResults[0]
+	e.waitResults = e.waitResults[1:]
+	return result.info, result.err
+}
+
+// fakeDb keeps the in-flight nonces and journal entries in memory and remembers what was changed.
+type fakeDb struct {
+	repository.DbRepository
+	inFlight     map[uint64]models.InFlightNonce
+	transactions []models.Transaction
+	moved        map[string]repository.TransactionInclusion
+	reopened     []string
+	finished     map[primitive.ObjectID]string
+	recorded     []string
+}
+
+func newFakeDb() *fakeDb {
+	return &fakeDb{
+		inFlight: make(map[uint64]models.InFlightNonce),
+		moved:    make(map[string]repository.TransactionInclusion),
+		finished: make(map[primitive.ObjectID]string),
+	}
+}
+
+func (d *fakeDb) GetInFlightNonces(ctx context.Context, address string) ([]models.InFlightNonce, error) {
+	var nonces []models.InFlightNonce
+	for _, nonce := range d.inFlight {
+		if nonce.OperatorAddress == address {
+			nonces = append(nonces, nonce)
+		}
+	}
+	return nonces, nil
+}
+
+func (d *fakeDb) SaveInFlightNonce(ctx context.Context, nonce models.InFlightNonce) error {
+	d.inFlight[nonce.Nonce] = nonce
+	return nil
+}
+
+func (d *fakeDb) DeleteInFlightNonce(ctx context.Context, address string, nonce uint64) error {
+	delete(d.inFlight, nonce)
+	return nil
+}
+
+func (d *fakeDb) GetOperator(ctx context.Context, address string) (models.Operator, error) {
+	return models.Operator{}, mongo.ErrNoDocuments
+}
+
+func (d *fakeDb) GetMinedTransactions(ctx context.Context, fromBlock uint64) ([]models.Transaction, error) {
+	var mined []models.Transaction
+	for _, transaction := range d.transactions {
+		if transaction.State == models.TransactionStateMined && transaction.BlockNumber >= fromBlock {
+			mined = append(mined, transaction)
+		}
+	}
+	return mined, nil
+}
+
+func (d *fakeDb) MoveTransaction(ctx context.Context, transactionHash string, inclusion repository.TransactionInclusion) error {
+	d.moved[transactionHash] = inclusion
+	return nil
+}
+
+func (d *fakeDb) ReopenTransaction(ctx context.Context, transactionHash string) error {
+	d.reopened = append(d.reopened, transactionHash)
+	for i := range d.transactions {
+		if d.transactions[i].TransactionHash == transactionHash && d.transactions[i].State == models.TransactionStateMined {
+			d.transactions[i].State = models.TransactionStateBroadcast
+		}
+	}
+	return nil
+}
+
+func (d *fakeDb) GetTransactionGroup(ctx context.Context, groupID primitive.ObjectID) ([]models.Transaction, error) {
+	var group []models.Transaction
+	for _, transaction := range d.transactions {
+		if transaction.GroupID == groupID {
+			group = append(group, transaction)
+		}
+	}
+	return group, nil
+}
+
+func (d *fakeDb) FinishTransaction(ctx context.Context, groupID primitive.ObjectID, transactionInfo repository.TransactionInfo, state string, errorMessage string) error {
+	d.finished[groupID] = state
+	return nil
+}
+
+func (d *fakeDb) MarkTransactionRecorded(ctx context.Context, transactionHash string) error {
+	d.recorded = append(d.recorded, transactionHash)
+	return nil
+}
+
+func newTestService(cfg *config.Config, db *fakeDb, eth *fakeEth) *boostService {
+	var dbRepository repository.DbRepository = db
+	var ethRepository repository.EthRepository = eth
+	return &boostService{config: cfg, dbRepository: &dbRepository, ethRepository: &ethRepository}
+}
+
+// testTransaction builds an unsigned transaction with the nonce, enough for the journal and hashes.
+func testTransaction(nonce uint64, feeCap int64, tip int64) *types.Transaction {
+	to := common.HexToAddress("0x2222222222222222222222222222222222222222")
+	return types.NewTx(&types.DynamicFeeTx{
+		Nonce:     nonce,
+		GasFeeCap: big.NewInt(feeCap),
+		GasTipCap: big.NewInt(tip),
+		Gas:       100000,
+		To:        &to,
+		Value:     big.NewInt(0),
+	})
+}
@@ -0,0 +1,82 @@
+package services
+
+import (
+	"bgt_boost/internal/models"
+	"bgt_boost/internal/repository"
+	"bgt_boost/internal/utils"
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+)
+
+const (
+	// TipSourceSuggest takes the tip from eth_maxPriorityFeePerGas
+	TipSourceSuggest = "suggest"
+	// TipSourceFeeHistory takes the tip from eth_feeHistory reward percentiles
+	TipSourceFeeHistory = "fee_history"
+)
+
+// suggestFees prices a transaction: the tip from suggestTip, and a fee cap of the latest base fee times
+// BASE_FEE_MULTIPLIER plus the tip, so that the transaction stays valid while the base fee rises for a few blocks.
+func (s *boostService) suggestFees(ctx context.Context) (repository.TransactionFees, error) {
+	baseFee, err := (*s.ethRepository).GetBaseFee(ctx)
+	if err != nil {
+		return repository.TransactionFees{}, err
+	}
+	tip, err := s.suggestTip(ctx)
+	if err != nil {
+		return repository.TransactionFees{}, err
+	}
+	feeCap, _ := new(big.Float).Mul(new(big.Float).SetInt(baseFee), big.NewFloat(s.config.BaseFeeMultiplier)).Int(nil)
+	feeCap.Add(feeCap, tip)
+	return repository.TransactionFees{GasFeeCap: feeCap, GasTipCap: tip}, nil
+}
+
+// suggestTip returns the priority fee to pay, either as suggested by the RPC or as the median over the latest blocks
+// of the TIP_PERCENTILE tip, clamped to MIN_TIP_GWEI and MAX_TIP_GWEI.
+func (s *boostService) suggestTip(ctx context.Context) (*big.Int, error) {
+	var tip *big.Int
+	switch s.config.TipSource {
+	case TipSourceFeeHistory:
+		tips, err := (*s.ethRepository).GetFeeHistoryTips(ctx, s.config.FeeHistoryBlocks, s.config.TipPercentile)
+		if err != nil {
+			return nil, err
+		}
+		if len(tips) == 0 {
+			return nil, fmt.Errorf("fee history returned no tips")
+		}
+		sort.Slice(tips, func(i, j int) bool { return tips[i].Cmp(tips[j]) < 0 })
+		tip = new(big.Int).Set(tips[len(tips)/2])
+	case TipSourceSuggest:
+		suggested, err := (*s.ethRepository).SuggestGasTipCap(ctx)
+		if err != nil {
+			return nil, err
+		}
+		tip = new(big.Int).Set(suggested)
+	default:
+		return nil, fmt.Errorf("unknown tip source %q", s.config.TipSource)
+	}
+
+	if minTip := utils.ConvertGweiToWei(s.config.MinTipGwei); tip.Cmp(minTip) < 0 {
+		tip = minTip
+	}
+	if s.config.MaxTipGwei > 0 {
+		if maxTip := utils.ConvertGweiToWei(s.config.MaxTipGwei); tip.Cmp(maxTip) > 0 {
+			tip = maxTip
+		}
+	}
+	return tip, nil
+}
+
+// transactionFees is what a mined transaction was willing to pay and what it paid per gas, as stored with its records.
+func transactionFees(transactionInfo repository.TransactionInfo) *models.TransactionFees {
+	if transactionInfo.Fees.GasFeeCap == nil || transactionInfo.EffectiveGasPrice == nil {
+		return nil
+	}
+	return &models.TransactionFees{
+		GasFeeCap:         transactionInfo.Fees.GasFeeCap.String(),
+		GasTipCap:         transactionInfo.Fees.GasTipCap.String(),
+		EffectiveGasPrice: transactionInfo.EffectiveGasPrice.String(),
+	}
+}
@@ -0,0 +1,91 @@
+package services
+
+import (
+	"bgt_boost/internal/models"
+	"bgt_boost/internal/utils"
+	"context"
+	"fmt"
+	"log"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+type DeferredAction struct {
+	Method string `json:"method"`
+	Amount string `json:"amount"`
+	Reason string `json:"reason"`
+}
+
+// applyGasGuard moves the decision's actions that are too expensive at current network fees to its deferred list.
+// They are planned again, and retried, on the next run.
+func (s *boostService) applyGasGuard(ctx context.Context, d *decision, operator models.Operator, state runState) {
+	var actions []action
+	activationDeferred := false
+	for _, a := range d.Actions {
+		reason := s.gasGuardReason(ctx, a, operator, state)
+		if reason == "" && a.Method == methodQueueBoost && activationDeferred {
+			// Queueing now would reset the pending boost that is waiting for activation
+			reason = "activation of the pending boost was deferred"
+		}
+		if reason == "" {
+			actions = append(actions, a)
+			continue
+		}
+
+		log.Printf("Deferring %s for validator %s: %s", a.Method, a.Validator.Pubkey, reason)
+		if a.Method == methodActivateBoost {
+			activationDeferred = true
+		}
+		d.Deferred = append(d.Deferred, DeferredAction{
+			Method: a.Method,
+			Amount: a.Amount.String(),
+			Reason: reason,
+		})
+	}
+	d.Actions = actions
+}
+
+// gasGuardReason explains why the action should be deferred, or returns an empty string when it can be sent. The
+// operator's own base fee limits take precedence over the configured ones.
+func (s *boostService) gasGuardReason(ctx context.Context, a action, operator models.Operator, state runState) string {
+	maxBaseFee := s.config.MaxBaseFeeGwei
+	if operator.MaxBaseFeeGwei > 0 {
+		maxBaseFee = operator.MaxBaseFeeGwei
+	}
+	if a.Method == methodActivateBoost {
+		maxBaseFee = s.config.MaxActivationBaseFeeGwei
+		if operator.MaxActivationBaseFeeGwei > 0 {
+			maxBaseFee = operator.MaxActivationBaseFeeGwei
+		}
+	}
+	baseFee := utils.ConvertWeiToGwei(state.BaseFee)
+	if maxBaseFee > 0 && baseFee > maxBaseFee {
+		return fmt.Sprintf("base fee %.4f gwei is above the %.4f gwei limit", baseFee, maxBaseFee)
+	}
+
+	if a.Method == methodQueueBoost && s.config.MaxFeeToBoostRatio > 0 {
+		feePerGas := new(big.Int).Add(state.BaseFee, state.GasTipCap)
+		gas := s.estimateActionGas(ctx, a, operator)
+		fee := utils.ConvertWeiToEther(feePerGas.Mul(feePerGas, new(big.Int).SetUint64(gas)))
+		amount := utils.ConvertWeiToEther(a.Amount)
+		if amount > 0 && fee/amount > s.config.MaxFeeToBoostRatio {
+			return fmt.Sprintf("estimated fee of %f BERA is %f of the %f BGT queued, above the %f ratio limit", fee, fee/amount, amount, s.config.MaxFeeToBoostRatio)
+		}
+	}
+	return ""
+}
+
+// estimateActionGas estimates the gas of the action's call, or returns the most it may use, the limit for its method,
+// when it cannot be estimated.
+func (s *boostService) estimateActionGas(ctx context.Context, a action, operator models.Operator) uint64 {
+	gas, err := (*s.ethRepository).EstimateGas(ctx, common.HexToAddress(operator.Address), s.config.BGTContract.Address, a.Data)
+	if err == nil {
+		return gas
+	}
+	log.Printf("Failed to estimate gas of %s for validator %s: %v", a.Method, a.Validator.Pubkey, err)
+	if limit, ok := s.config.MethodGasLimits[a.Method]; ok {
+		return limit
+	}
+	return uint64(s.config.GasLimit)
+}
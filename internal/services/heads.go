@@ -0,0 +1,70 @@
+package services
+
+import (
+	"bgt_boost/internal/repository"
+	"context"
+	"log"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// watchHeads sends the number of every new block to heads until ctx is cancelled. It subscribes to new heads and
+// falls back to polling the latest block every pollSeconds when the RPC does not support subscriptions, as plain HTTP
+// endpoints don't.
+func watchHeads(ctx context.Context, ethRepository *repository.EthRepository, pollSeconds int, heads chan<- uint64) {
+	headers := make(chan *types.Header)
+	sub, err := (*ethRepository).SubscribeNewHeads(ctx, headers)
+	if err != nil {
+		log.Printf("New head subscription unavailable, polling every %ds: %v", pollSeconds, err)
+		pollHeads(ctx, ethRepository, pollSeconds, heads)
+		return
+	}
+	defer sub.Unsubscribe()
+	log.Println("Subscribed to new heads")
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case err := <-sub.Err():
+			log.Printf("New head subscription failed, polling every %ds: %v", pollSeconds, err)
+			pollHeads(ctx, ethRepository, pollSeconds, heads)
+			return
+		case header := <-headers:
+			select {
+			case heads <- header.Number.Uint64():
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+func pollHeads(ctx context.Context, ethRepository *repository.EthRepository, pollSeconds int, heads chan<- uint64) {
+	ticker := time.NewTicker(time.Duration(pollSeconds) * time.Second)
+	defer ticker.Stop()
+
+	var lastBlock uint64
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			block, err := (*ethRepository).GetLatestBlock(ctx)
+			if err != nil {
+				log.Printf("Failed to poll latest block: %v", err)
+				continue
+			}
+			if block <= lastBlock {
+				continue
+			}
+			lastBlock = block
+			select {
+			case heads <- block:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,283 @@
+package services
+
+import (
+	"bgt_boost/internal/models"
+	"bgt_boost/internal/repository"
+	"context"
+	"fmt"
+	"log"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type BackfillReport struct {
+	FromBlock uint64 `json:"fromBlock"`
+	ToBlock   uint64 `json:"toBlock"`
+	Logs      int    `json:"logs"`
+	Stored    int    `json:"stored"`
+}
+
+// Backfill indexes the BGT boost events of our operators and validators between fromBlock and toBlock in chunks of
+// INDEXER_CHUNK_SIZE blocks. Without fromBlock it continues after the persisted checkpoint, and it never goes past the
+// latest block that has CONFIRMATION_BLOCKS blocks on top of it, so a reorg cannot undo indexed events. The
+// checkpoint only moves forward when the range continues from it, so indexing an older range never skips blocks.
+func (s *boostService) Backfill(ctx context.Context, fromBlock *uint64, toBlock *uint64) (BackfillReport, error) {
+	s.indexerLock.Lock()
+	defer s.indexerLock.Unlock()
+
+	checkpoint, err := (*s.dbRepository).GetIndexerCheckpoint(ctx)
+	if err != nil {
+		return BackfillReport{}, err
+	}
+	report := BackfillReport{FromBlock: max(checkpoint+1, s.config.IndexerStartBlock)}
+	if fromBlock != nil {
+		report.FromBlock = *fromBlock
+	}
+	latestBlock, err := (*s.ethRepository).GetLatestBlock(ctx)
+	if err != nil {
+		return report, err
+	}
+	if latestBlock < uint64(s.config.ConfirmationBlocks) {
+		return report, nil
+	}
+	report.ToBlock = latestBlock - uint64(s.config.ConfirmationBlocks)
+	if toBlock != nil {
+		report.ToBlock = min(*toBlock, report.ToBlock)
+	}
+	if report.FromBlock > report.ToBlock {
+		return report, nil
+	}
+
+	validators, err := (*s.dbRepository).GetValidators(ctx)
+	if err != nil {
+		return report, err
+	}
+	operators, err := (*s.dbRepository).GetOperators(ctx)
+	if err != nil {
+		return report, err
+	}
+	// Pubkeys are indexed as their hash, so logs can only be matched to validators we know
+	validatorsByTopic := make(map[common.Hash]models.Validator, len(validators))
+	pubkeyTopics := make([]common.Hash, len(validators))
+	for i, validator := range validators {
+		pubkeyTopics[i] = crypto.Keccak256Hash(common.FromHex(validator.Pubkey))
+		validatorsByTopic[pubkeyTopics[i]] = validator
+	}
+	operatorAddresses := make([]common.Address, len(operators))
+	for i, operator := range operators {
+		operatorAddresses[i] = common.HexToAddress(operator.Address)
+	}
+
+	advanceCheckpoint := report.FromBlock <= checkpoint+1
+	chunkSize := uint64(max(1, s.config.IndexerChunkSize))
+	timestamps := make(map[uint64]time.Time)
+	for start := report.FromBlock; start <= report.ToBlock; start += chunkSize {
+		end := min(start+chunkSize-1, report.ToBlock)
+		logs, err := (*s.ethRepository).GetBoostLogs(ctx, start, end, operatorAddresses, pubkeyTopics)
+		if err != nil {
+			return report, err
+		}
+		for _, l := range logs {
+			stored, err := s.indexLog(ctx, l, validatorsByTopic, timestamps)
+			if err != nil {
+				return report, fmt.Errorf("failed to index log %d of %s: %w", l.Index, l.TxHash.Hex(), err)
+			}
+			report.Logs++
+			if stored {
+				report.Stored++
+			}
+		}
+		if advanceCheckpoint && end > checkpoint {
+			if err := (*s.dbRepository).SaveIndexerCheckpoint(ctx, end); err != nil {
+				return report, err
+			}
+			checkpoint = end
+		}
+		log.Printf("Indexed blocks %d-%d: %d boost events", start, end, len(logs))
+	}
+	return report, nil
+}
+
+// indexLog stores the boost event and reports whether it was new. It holds the operator's lock so that a transaction
+// the engine is still recording is not stored twice. New events update queue statuses and drop boost requests the
+// same way as the engine's own transactions.
+func (s *boostService) indexLog(ctx context.Context, l types.Log, validatorsByTopic map[common.Hash]models.Validator, timestamps map[uint64]time.Time) (bool, error) {
+	event, err := s.config.BGTContract.ABI.EventByID(l.Topics[0])
+	if err != nil {
+		return false, err
+	}
+	sender, user, pubkeyTopic := l.Topics[1], l.Topics[1], l.Topics[2]
+	if event.Name == "ActivateBoost" || event.Name == "DropBoost" {
+		user, pubkeyTopic = l.Topics[2], l.Topics[3]
+	}
+	validator, ok := validatorsByTopic[pubkeyTopic]
+	if !ok {
+		return false, nil
+	}
+	values, err := s.config.BGTContract.ABI.Unpack(event.Name, l.Data)
+	if err != nil {
+		return false, err
+	}
+	amount := values[0].(*big.Int).String()
+	timestamp, ok := timestamps[l.BlockNumber]
+	if !ok {
+		timestamp, err = (*s.ethRepository).GetBlockTimestamp(ctx, l.BlockNumber)
+		if err != nil {
+			return false, err
+		}
+		timestamps[l.BlockNumber] = timestamp
+	}
+
+	operatorAddress := common.BytesToAddress(user.Bytes()).Hex()
+	unlock := s.operatorLocks.lock(operatorAddress)
+	defer unlock()
+
+	logIndex := l.Index
+	transactionHash := l.TxHash.Hex()
+	transactionFrom := common.BytesToAddress(sender.Bytes()).Hex()
+	toContract := s.config.BGTContract.Address.Hex()
+	indexedLog := repository.IndexedLog{
+		TransactionHash: transactionHash,
+		LogIndex:        logIndex,
+		ValidatorPubkey: validator.Pubkey,
+		BlockNumber:     l.BlockNumber,
+		Amount:          amount,
+	}
+	switch event.Name {
+	case "QueueBoost":
+		indexedLog.Record = models.QueueBoost{
+			ValidatorPubkey: validator.Pubkey,
+			OperatorAddress: operatorAddress,
+			BlockNumber:     l.BlockNumber,
+			BlockHash:       l.BlockHash.Hex(),
+			Amount:          amount,
+			TransactionHash: transactionHash,
+			BlockTimestamp:  timestamp,
+			TransactionFrom: transactionFrom,
+			ToContract:      toContract,
+			Source:          models.SourceIndexer,
+			LogIndex:        &logIndex,
+			Status:          models.QueueBoostStatusPending,
+		}
+		stored, err := (*s.dbRepository).SaveIndexedLog(ctx, indexedLog)
+		if err != nil || !stored {
+			return stored, err
+		}
+		return true, (*s.dbRepository).MarkQueueBoostsSuperseded(ctx, validator.Pubkey, transactionHash, l.BlockNumber)
+	case "ActivateBoost":
+		queueBoosts, err := (*s.dbRepository).GetInActiveBoosts(ctx, validator.Pubkey, l.BlockNumber)
+		if err != nil {
+			return false, err
+		}
+		queueBoostIDs := make([]primitive.ObjectID, len(queueBoosts))
+		for i, queueBoost := range queueBoosts {
+			queueBoostIDs[i] = queueBoost.ID
+		}
+		indexedLog.Record = models.ActivateBoost{
+			Amount:          amount,
+			ValidatorPubkey: validator.Pubkey,
+			OperatorAddress: operatorAddress,
+			TransactionHash: transactionHash,
+			BlockNumber:     l.BlockNumber,
+			BlockHash:       l.BlockHash.Hex(),
+			BlockTimestamp:  timestamp,
+			TransactionFrom: transactionFrom,
+			ToContract:      toContract,
+			Source:          models.SourceIndexer,
+			LogIndex:        &logIndex,
+			QueueBoostIDs:   queueBoostIDs,
+		}
+		stored, err := (*s.dbRepository).SaveIndexedLog(ctx, indexedLog)
+		if err != nil || !stored {
+			return stored, err
+		}
+		return true, (*s.dbRepository).MarkBoostsAsActivated(ctx, queueBoostIDs, transactionHash, l.BlockNumber)
+	case "CancelBoost":
+		indexedLog.Record = models.CancelBoost{
+			Amount:          amount,
+			ValidatorPubkey: validator.Pubkey,
+			OperatorAddress: operatorAddress,
+			TransactionHash: transactionHash,
+			BlockNumber:     l.BlockNumber,
+			BlockTimestamp:  timestamp,
+			TransactionFrom: transactionFrom,
+			ToContract:      toContract,
+			Source:          models.SourceIndexer,
+			LogIndex:        &logIndex,
+		}
+		stored, err := (*s.dbRepository).SaveIndexedLog(ctx, indexedLog)
+		if err != nil || !stored {
+			return stored, err
+		}
+		// Whether the cancel emptied the queue is not known here, so it is taken out of the latest records
+		return true, s.cancelQueuedAmount(ctx, validator, values[0].(*big.Int), repository.TransactionInfo{
+			TransactionHash: transactionHash,
+			BlockNumber:     l.BlockNumber,
+		})
+	case "QueueDropBoost":
+		indexedLog.Record = models.QueueDropBoost{
+			ValidatorPubkey: validator.Pubkey,
+			OperatorAddress: operatorAddress,
+			BlockNumber:     l.BlockNumber,
+			Amount:          amount,
+			TransactionHash: transactionHash,
+			BlockTimestamp:  timestamp,
+			TransactionFrom: transactionFrom,
+			ToContract:      toContract,
+			Source:          models.SourceIndexer,
+			LogIndex:        &logIndex,
+		}
+		stored, err := (*s.dbRepository).SaveIndexedLog(ctx, indexedLog)
+		if err != nil || !stored {
+			return stored, err
+		}
+		requestIDs, err := s.coveredRequests(ctx, validator, models.DropBoostRequestStatusPending, values[0].(*big.Int))
+		if err != nil {
+			return true, err
+		}
+		return true, (*s.dbRepository).MarkDropBoostRequestsQueued(ctx, requestIDs, transactionHash)
+	case "DropBoost":
+		indexedLog.Record = models.DropBoost{
+			Amount:          amount,
+			ValidatorPubkey: validator.Pubkey,
+			OperatorAddress: operatorAddress,
+			TransactionHash: transactionHash,
+			BlockNumber:     l.BlockNumber,
+			BlockTimestamp:  timestamp,
+			TransactionFrom: transactionFrom,
+			ToContract:      toContract,
+			Source:          models.SourceIndexer,
+			LogIndex:        &logIndex,
+		}
+		stored, err := (*s.dbRepository).SaveIndexedLog(ctx, indexedLog)
+		if err != nil || !stored {
+			return stored, err
+		}
+		requestIDs, err := s.coveredRequests(ctx, validator, models.DropBoostRequestStatusQueued, values[0].(*big.Int))
+		if err != nil {
+			return true, err
+		}
+		return true, (*s.dbRepository).MarkDropBoostRequestsDropped(ctx, requestIDs, transactionHash)
+	case "CancelDropBoost":
+		indexedLog.Record = models.CancelDropBoost{
+			Amount:          amount,
+			ValidatorPubkey: validator.Pubkey,
+			OperatorAddress: operatorAddress,
+			TransactionHash: transactionHash,
+			BlockNumber:     l.BlockNumber,
+			BlockTimestamp:  timestamp,
+			TransactionFrom: transactionFrom,
+			ToContract:      toContract,
+			Source:          models.SourceIndexer,
+			LogIndex:        &logIndex,
+		}
+	default:
+		return false, fmt.Errorf("unexpected event: %s", event.Name)
+	}
+	return (*s.dbRepository).SaveIndexedLog(ctx, indexedLog)
+}
@@ -0,0 +1,301 @@
+package services
+
+import (
+	"bgt_boost/internal/models"
+	"bgt_boost/internal/repository"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// pendingTransaction is a broadcast transaction waiting to be mined, along with the replacements signed for its nonce.
+type pendingTransaction struct {
+	// journal is the entry of the latest transaction signed for the nonce
+	journal  models.Transaction
+	signedTx *types.Transaction
+	// transactionHashes are every transaction of the group that may still be mined
+	transactionHashes []common.Hash
+	replacement       models.TransactionReplacement
+}
+
+func newPendingTransaction(journal models.Transaction, signedTx *types.Transaction) *pendingTransaction {
+	return &pendingTransaction{
+		journal:           journal,
+		signedTx:          signedTx,
+		transactionHashes: []common.Hash{signedTx.Hash()},
+		replacement: models.TransactionReplacement{
+			OperatorAddress:         journal.OperatorAddress,
+			Nonce:                   journal.Nonce,
+			OriginalTransactionHash: signedTx.Hash().Hex(),
+		},
+	}
+}
+
+// replaceWith makes the replacement the transaction the pending transaction is tracked by.
+func (p *pendingTransaction) replaceWith(journal models.Transaction, signedTx *types.Transaction) {
+	p.journal = journal
+	p.signedTx = signedTx
+	p.transactionHashes = append(p.transactionHashes, signedTx.Hash())
+	p.replacement.ReplacementTransactionHashes = append(p.replacement.ReplacementTransactionHashes, signedTx.Hash().Hex())
+}
+
+func transactionIntent(actions []action) []models.TransactionAction {
+	intent := make([]models.TransactionAction, len(actions))
+	for i, a := range actions {
+		intent[i] = models.TransactionAction{
+			Method:              a.Method,
+			ValidatorPubkey:     a.Validator.Pubkey,
+			Amount:              a.Amount.String(),
+			DropBoostRequestIDs: a.dropBoostRequestIDs,
+			CancelsQueue:        a.cancelsQueue,
+		}
+	}
+	return intent
+}
+
+// journalTransaction records the unsigned transaction as built, in the given group or, for a zero group ID, in a new
+// group of its own.
+func (s *boostService) journalTransaction(ctx context.Context, from common.Address, tx *types.Transaction, intent []models.TransactionAction, groupID primitive.ObjectID) (models.Transaction, error) {
+	unsignedTx, err := tx.MarshalBinary()
+	if err != nil {
+		return models.Transaction{}, fmt.Errorf("failed to encode transaction: %w", err)
+	}
+	id := primitive.NewObjectID()
+	if groupID.IsZero() {
+		groupID = id
+	}
+	journal := models.Transaction{
+		ID:                  id,
+		GroupID:             groupID,
+		OperatorAddress:     from.Hex(),
+		Nonce:               tx.Nonce(),
+		Intent:              intent,
+		UnsignedTransaction: hexutil.Encode(unsignedTx),
+		State:               models.TransactionStateBuilt,
+	}
+	if err := (*s.dbRepository).AddTransaction(ctx, journal); err != nil {
+		return models.Transaction{}, fmt.Errorf("failed to journal transaction: %w", err)
+	}
+	return journal, nil
+}
+
+// signJournaled signs the transaction of the journal entry and records the signed transaction. The entry is marked
+// failed when signing fails.
+func (s *boostService) signJournaled(ctx context.Context, operator models.Operator, journal *models.Transaction, tx *types.Transaction) (*types.Transaction, error) {
+	signedTx, err := s.signTransaction(ctx, operator, tx)
+	if err != nil {
+		s.markJournaled(ctx, journal.ID, models.TransactionStateFailed, err)
+		return nil, err
+	}
+	rawTx, err := signedTx.MarshalBinary()
+	if err != nil {
+		s.markJournaled(ctx, journal.ID, models.TransactionStateFailed, err)
+		return nil, fmt.Errorf("failed to encode signed transaction: %w", err)
+	}
+	journal.SignedTransaction = hexutil.Encode(rawTx)
+	journal.TransactionHash = signedTx.Hash().Hex()
+	journal.State = models.TransactionStateSigned
+	if err := (*s.dbRepository).MarkTransactionSigned(ctx, journal.ID, journal.SignedTransaction, journal.TransactionHash); err != nil {
+		return nil, fmt.Errorf("failed to journal signed transaction: %w", err)
+	}
+	return signedTx, nil
+}
+
+func (s *boostService) markJournaled(ctx context.Context, id primitive.ObjectID, state string, cause error) {
+	errorMessage := ""
+	if cause != nil {
+		errorMessage = cause.Error()
+	}
+	if err := (*s.dbRepository).MarkTransactionState(ctx, id, state, errorMessage); err != nil {
+		log.Printf("Failed to mark transaction %s %s: %v", id.Hex(), state, err)
+	}
+}
+
+func (s *boostService) markTransactionRecorded(ctx context.Context, transactionInfo repository.TransactionInfo) {
+	if err := (*s.dbRepository).MarkTransactionRecorded(ctx, transactionInfo.TransactionHash); err != nil {
+		log.Printf("Failed to mark transaction %s recorded: %v", transactionInfo.TransactionHash, err)
+	}
+}
+
+// finishTransaction waits for the pending transaction and settles its journal entries and nonce.
+func (s *boostService) finishTransaction(ctx context.Context, operator models.Operator, pending *pendingTransaction) (repository.TransactionInfo, error) {
+	from := common.HexToAddress(operator.Address)
+	txInfo, err := s.waitForTransaction(ctx, operator, pending)
+	if err != nil && !errors.Is(err, repository.ErrTransactionReverted) && !errors.Is(err, ErrNonceConsumed) {
+		s.nonces.invalidate(from)
+		return repository.TransactionInfo{}, fmt.Errorf("failed to wait for transaction to be mined: %w", err)
+	}
+
+	state, errorMessage := models.TransactionStateMined, ""
+	if err != nil {
+		state, errorMessage = models.TransactionStateFailed, err.Error()
+	}
+	if errors.Is(err, ErrNonceConsumed) {
+		txInfo.TransactionHash = pending.journal.TransactionHash
+		s.nonces.invalidate(from)
+	}
+	if err := (*s.dbRepository).FinishTransaction(ctx, pending.journal.GroupID, txInfo, state, errorMessage); err != nil {
+		log.Printf("Failed to mark transaction %s %s: %v", txInfo.TransactionHash, state, err)
+	}
+	s.releaseNonce(ctx, from, pending.journal.Nonce)
+	if err != nil {
+		return repository.TransactionInfo{}, fmt.Errorf("failed to send transaction: %w", err)
+	}
+	return txInfo, nil
+}
+
+// ResumeTransactions finishes what a previous process left in the transactions journal.
+func (s *boostService) ResumeTransactions(ctx context.Context) error {
+	transactions, err := (*s.dbRepository).GetUnfinishedTransactions(ctx)
+	if err != nil {
+		return err
+	}
+	groups := groupTransactions(transactions)
+	if len(groups) > 0 {
+		log.Printf("Resuming %d unfinished transactions", len(groups))
+	}
+	for _, group := range groups {
+		if err := s.resumeTransaction(ctx, group); err != nil {
+			log.Printf("Failed to resume transaction %s: %v", group[0].GroupID.Hex(), err)
+		}
+	}
+	return nil
+}
+
+// resumeOperatorTransactions finishes the operator's unfinished groups; the caller holds the operator's lock.
+func (s *boostService) resumeOperatorTransactions(ctx context.Context, operator models.Operator) error {
+	transactions, err := (*s.dbRepository).GetOperatorUnfinishedTransactions(ctx, common.HexToAddress(operator.Address).Hex())
+	if err != nil {
+		return err
+	}
+	for _, group := range groupTransactions(transactions) {
+		if err := s.resumeGroup(ctx, operator, group); err != nil {
+			return fmt.Errorf("failed to resume transaction %s: %w", group[0].GroupID.Hex(), err)
+		}
+	}
+	return nil
+}
+
+// groupTransactions splits journal entries by group, keeping the order the groups and their entries came in.
+func groupTransactions(transactions []models.Transaction) [][]models.Transaction {
+	var groups [][]models.Transaction
+	index := make(map[primitive.ObjectID]int)
+	for _, transaction := range transactions {
+		i, ok := index[transaction.GroupID]
+		if !ok {
+			i = len(groups)
+			index[transaction.GroupID] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], transaction)
+	}
+	return groups
+}
+
+// resumeTransaction finishes one group of journal entries, the first transaction signed for an intent and its
+// replacements, in the order they were signed.
+func (s *boostService) resumeTransaction(ctx context.Context, group []models.Transaction) error {
+	operator, err := s.getOperator(ctx, group[0].OperatorAddress)
+	if err != nil {
+		return err
+	}
+	unlock := s.operatorLocks.lock(operator.Address)
+	defer unlock()
+	return s.resumeGroup(ctx, operator, group)
+}
+
+// resumeGroup is resumeTransaction for a caller that holds the operator's lock.
+func (s *boostService) resumeGroup(ctx context.Context, operator models.Operator, group []models.Transaction) error {
+	var pending *pendingTransaction
+	var replaced []common.Hash
+	for _, transaction := range group {
+		switch transaction.State {
+		case models.TransactionStateMined:
+			txInfo, err := (*s.ethRepository).WaitForTransaction(ctx, []common.Hash{common.HexToHash(transaction.TransactionHash)}, uint64(s.config.InclusionTimeoutBlocks))
+			if err != nil {
+				return err
+			}
+			return s.recordIntent(ctx, transaction.Intent, txInfo)
+		case models.TransactionStateBuilt:
+			s.markJournaled(ctx, transaction.ID, models.TransactionStateFailed, errors.New("interrupted before it was signed"))
+			continue
+		case models.TransactionStateReplaced:
+			// An earlier transaction for the nonce can still be the one that is mined
+			replaced = append(replaced, common.HexToHash(transaction.TransactionHash))
+			continue
+		case models.TransactionStateFailed:
+			continue
+		}
+
+		signedTx := new(types.Transaction)
+		if err := signedTx.UnmarshalBinary(common.FromHex(transaction.SignedTransaction)); err != nil {
+			s.markJournaled(ctx, transaction.ID, models.TransactionStateFailed, err)
+			continue
+		}
+		if transaction.State == models.TransactionStateSigned {
+			if err := (*s.ethRepository).BroadcastTransaction(ctx, signedTx); err != nil {
+				// An earlier broadcast that seemed to fail may have reached a pool, and the transaction may be mined
+				known, knownErr := (*s.ethRepository).IsTransactionKnown(ctx, signedTx.Hash())
+				if knownErr != nil {
+					return knownErr
+				}
+				if !known {
+					s.markJournaled(ctx, transaction.ID, models.TransactionStateFailed, err)
+					continue
+				}
+			}
+			s.markJournaled(ctx, transaction.ID, models.TransactionStateBroadcast, nil)
+		}
+		if pending == nil {
+			pending = newPendingTransaction(transaction, signedTx)
+		} else {
+			pending.replaceWith(transaction, signedTx)
+		}
+	}
+	if pending == nil {
+		return nil
+	}
+	pending.transactionHashes = append(replaced, pending.transactionHashes...)
+
+	log.Printf("Waiting for transaction %s of operator %s", pending.signedTx.Hash().Hex(), operator.Address)
+	txInfo, err := s.finishTransaction(ctx, operator, pending)
+	if err != nil {
+		return err
+	}
+	return s.recordIntent(ctx, pending.journal.Intent, txInfo)
+}
+
+// recordIntent writes the records of the actions a mined transaction made, as the engine would have after sending it.
+func (s *boostService) recordIntent(ctx context.Context, intent []models.TransactionAction, transactionInfo repository.TransactionInfo) error {
+	actions := make([]action, len(intent))
+	for i, intended := range intent {
+		validator, err := (*s.dbRepository).GetValidator(ctx, intended.ValidatorPubkey)
+		if err != nil {
+			return err
+		}
+		amount, ok := new(big.Int).SetString(intended.Amount, 10)
+		if !ok {
+			return fmt.Errorf("invalid amount %q for %s", intended.Amount, intended.Method)
+		}
+		actions[i] = action{
+			Method:              intended.Method,
+			Validator:           validator,
+			Amount:              amount,
+			dropBoostRequestIDs: intended.DropBoostRequestIDs,
+			cancelsQueue:        intended.CancelsQueue,
+		}
+	}
+	if err := errors.Join(s.recordActions(ctx, actions, transactionInfo)...); err != nil {
+		return err
+	}
+	log.Printf("Recorded transaction %s", transactionInfo.TransactionHash)
+	s.markTransactionRecorded(ctx, transactionInfo)
+	return nil
+}
@@ -0,0 +1,168 @@
+package services
+
+import (
+	"bgt_boost/internal/models"
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// operatorNonces is the local view of one operator's nonces. Its mutex is held while nonces are synced and handed
+// out, so that syncing one operator with the chain never holds up another.
+type operatorNonces struct {
+	mutex  sync.Mutex
+	synced bool
+	// next is the nonce after the highest one in flight
+	next uint64
+	// gaps are nonces below next that no in-flight transaction holds, reused before next
+	gaps []uint64
+}
+
+// nonceManager hands out operator nonces without asking the node for every transaction. It syncs with the chain on
+// first use and after any send error, and keeps the nonces of unmined transactions in the nonces collection so that
+// a restart does not reuse them.
+type nonceManager struct {
+	// mutex guards operators
+	mutex     sync.Mutex
+	operators map[common.Address]*operatorNonces
+}
+
+func (m *nonceManager) get(address common.Address) *operatorNonces {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.operators == nil {
+		m.operators = make(map[common.Address]*operatorNonces)
+	}
+	nonces, ok := m.operators[address]
+	if !ok {
+		nonces = &operatorNonces{}
+		m.operators[address] = nonces
+	}
+	return nonces
+}
+
+// invalidate makes the next reservation sync the operator's nonces with the chain again.
+func (m *nonceManager) invalidate(address common.Address) {
+	nonces := m.get(address)
+	nonces.mutex.Lock()
+	defer nonces.mutex.Unlock()
+	nonces.synced = false
+}
+
+// syncNonces rebuilds the operator's nonces from the chain and the in-flight records, dropping the stale ones.
+func (s *boostService) syncNonces(ctx context.Context, address common.Address, nonces *operatorNonces) error {
+	confirmed, pending, err := (*s.ethRepository).GetNonces(ctx, address)
+	if err != nil {
+		return err
+	}
+	inFlight, err := (*s.dbRepository).GetInFlightNonces(ctx, address.Hex())
+	if err != nil {
+		return err
+	}
+
+	next := pending
+	held := make(map[uint64]bool)
+	for _, record := range inFlight {
+		if record.Nonce < confirmed {
+			if err := (*s.dbRepository).DeleteInFlightNonce(ctx, address.Hex(), record.Nonce); err != nil {
+				return err
+			}
+			continue
+		}
+		if record.Nonce >= pending {
+			known := false
+			if record.TransactionHash != "" {
+				known, err = (*s.ethRepository).IsTransactionKnown(ctx, common.HexToHash(record.TransactionHash))
+				if err != nil {
+					return err
+				}
+			}
+			if !known {
+				log.Printf("Nonce %d of operator %s is no longer in flight, releasing it", record.Nonce, address.Hex())
+				if err := (*s.dbRepository).DeleteInFlightNonce(ctx, address.Hex(), record.Nonce); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		held[record.Nonce] = true
+		next = max(next, record.Nonce+1)
+	}
+
+	var gaps []uint64
+	for nonce := pending; nonce < next; nonce++ {
+		if !held[nonce] {
+			gaps = append(gaps, nonce)
+		}
+	}
+	if len(gaps) > 0 {
+		log.Printf("Operator %s has nonce gaps %v below %d, filling them first", address.Hex(), gaps, next)
+	}
+
+	nonces.next = next
+	nonces.gaps = gaps
+	nonces.synced = true
+	return nil
+}
+
+// reserveNonce hands out the operator's next nonce and records it as in flight.
+func (s *boostService) reserveNonce(ctx context.Context, address common.Address) (uint64, error) {
+	nonces := s.nonces.get(address)
+	nonces.mutex.Lock()
+	defer nonces.mutex.Unlock()
+	if !nonces.synced {
+		if err := s.syncNonces(ctx, address, nonces); err != nil {
+			return 0, fmt.Errorf("failed to sync nonces: %w", err)
+		}
+	}
+
+	var nonce uint64
+	if len(nonces.gaps) > 0 {
+		sort.Slice(nonces.gaps, func(i, j int) bool { return nonces.gaps[i] < nonces.gaps[j] })
+		nonce = nonces.gaps[0]
+		nonces.gaps = nonces.gaps[1:]
+	} else {
+		nonce = nonces.next
+		nonces.next++
+	}
+	if err := (*s.dbRepository).SaveInFlightNonce(ctx, models.InFlightNonce{OperatorAddress: address.Hex(), Nonce: nonce}); err != nil {
+		nonces.synced = false
+		return 0, fmt.Errorf("failed to record in-flight nonce: %w", err)
+	}
+	return nonce, nil
+}
+
+// trackNonce records the hash of the signed transaction holding the nonce, so that a sync can tell whether it was
+// dropped.
+func (s *boostService) trackNonce(ctx context.Context, address common.Address, nonce uint64, transactionHash common.Hash) {
+	err := (*s.dbRepository).SaveInFlightNonce(ctx, models.InFlightNonce{
+		OperatorAddress: address.Hex(),
+		Nonce:           nonce,
+		TransactionHash: transactionHash.Hex(),
+	})
+	if err != nil {
+		log.Printf("Failed to record transaction %s for nonce %d of operator %s: %v", transactionHash.Hex(), nonce, address.Hex(), err)
+	}
+}
+
+// unreserveNonce hands a nonce that was never signed back, to be used by the operator's next transaction.
+func (s *boostService) unreserveNonce(ctx context.Context, address common.Address, nonce uint64) {
+	s.releaseNonce(ctx, address, nonce)
+	nonces := s.nonces.get(address)
+	nonces.mutex.Lock()
+	defer nonces.mutex.Unlock()
+	if nonces.synced {
+		nonces.gaps = append(nonces.gaps, nonce)
+	}
+}
+
+// releaseNonce removes the nonce from the in-flight records once its transaction is mined, or when it was never sent.
+func (s *boostService) releaseNonce(ctx context.Context, address common.Address, nonce uint64) {
+	if err := (*s.dbRepository).DeleteInFlightNonce(ctx, address.Hex(), nonce); err != nil {
+		log.Printf("Failed to release nonce %d of operator %s: %v", nonce, address.Hex(), err)
+	}
+}
@@ -0,0 +1,117 @@
+package services
+
+import (
+	"bgt_boost/internal/config"
+	"bgt_boost/internal/models"
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var nonceOperator = common.HexToAddress(testOperator)
+
+func inFlight(nonce uint64, transactionHash string) models.InFlightNonce {
+	return models.InFlightNonce{OperatorAddress: nonceOperator.Hex(), Nonce: nonce, TransactionHash: transactionHash}
+}
+
+// reserveNonces reserves count nonces in a row.
+func reserveNonces(t *testing.T, s *boostService, count int) []uint64 {
+	t.Helper()
+	var nonces []uint64
+	for range count {
+		nonce, err := s.reserveNonce(context.Background(), nonceOperator)
+		if err != nil {
+			t.Fatalf("reserveNonce() error: %v", err)
+		}
+		nonces = append(nonces, nonce)
+	}
+	return nonces
+}
+
+func TestReserveNonceStartsAtPendingNonce(t *testing.T) {
+	db := newFakeDb()
+	s := newTestService(&config.Config{}, db, &fakeEth{confirmed: 5, pending: 7})
+
+	if got := reserveNonces(t, s, 3); !slices.Equal(got, []uint64{7, 8, 9}) {
+		t.Errorf("reserved %v, want 7, 8 and 9", got)
+	}
+	for _, nonce := range []uint64{7, 8, 9} {
+		if _, ok := db.inFlight[nonce]; !ok {
+			t.Errorf("nonce %d is not recorded as in flight", nonce)
+		}
+	}
+}
+
+func TestReserveNonceResumesAfterPersistedNonces(t *testing.T) {
+	// A restart finds the nonces the previous process had in flight, above what the endpoints report as pending
+	db := newFakeDb()
+	db.inFlight[10] = inFlight(10, "0x0a")
+	db.inFlight[11] = inFlight(11, "0x0b")
+	eth := &fakeEth{confirmed: 10, pending: 10, known: map[common.Hash]bool{
+		common.HexToHash("0x0a"): true,
+		common.HexToHash("0x0b"): true,
+	}}
+	s := newTestService(&config.Config{}, db, eth)
+
+	if got := reserveNonces(t, s, 1); got[0] != 12 {
+		t.Errorf("reserved %d after a restart, want 12", got[0])
+	}
+}
+
+func TestReserveNonceFillsGaps(t *testing.T) {
+	// Nonce 11 was handed out but never signed, so 12 is held above a gap
+	db := newFakeDb()
+	db.inFlight[10] = inFlight(10, "0x0a")
+	db.inFlight[12] = inFlight(12, "0x0c")
+	eth := &fakeEth{confirmed: 10, pending: 11, known: map[common.Hash]bool{common.HexToHash("0x0c"): true}}
+	s := newTestService(&config.Config{}, db, eth)
+
+	if got := reserveNonces(t, s, 2); !slices.Equal(got, []uint64{11, 13}) {
+		t.Errorf("reserved %v, want the gap 11 first and then 13", got)
+	}
+}
+
+func TestSyncNoncesReleasesStaleRecords(t *testing.T) {
+	db := newFakeDb()
+	// Mined: below the confirmed nonce
+	db.inFlight[3] = inFlight(3, "0x03")
+	// Dropped: at or above the pending nonce, and no endpoint knows the transaction
+	db.inFlight[5] = inFlight(5, "0x05")
+	// Reserved but never signed before a crash
+	db.inFlight[6] = inFlight(6, "")
+	// Still waiting in some endpoint's pool
+	db.inFlight[4] = inFlight(4, "0x04")
+	eth := &fakeEth{confirmed: 4, pending: 5, known: map[common.Hash]bool{common.HexToHash("0x04"): true}}
+	s := newTestService(&config.Config{}, db, eth)
+
+	nonces := s.nonces.get(nonceOperator)
+	if err := s.syncNonces(context.Background(), nonceOperator, nonces); err != nil {
+		t.Fatalf("syncNonces() error: %v", err)
+	}
+	if len(db.inFlight) != 1 {
+		t.Errorf("in-flight records %v, want only nonce 4 kept", db.inFlight)
+	}
+	if _, ok := db.inFlight[4]; !ok {
+		t.Errorf("nonce 4 was released while its transaction is pending")
+	}
+	if nonces.next != 5 || len(nonces.gaps) != 0 {
+		t.Errorf("next = %d with gaps %v, want 5 without gaps", nonces.next, nonces.gaps)
+	}
+}
+
+func TestUnreserveNonceHandsNonceBack(t *testing.T) {
+	db := newFakeDb()
+	s := newTestService(&config.Config{}, db, &fakeEth{confirmed: 20, pending: 20})
+	ctx := context.Background()
+
+	reserved := reserveNonces(t, s, 2)
+	s.unreserveNonce(ctx, nonceOperator, reserved[0])
+	if _, ok := db.inFlight[reserved[0]]; ok {
+		t.Errorf("nonce %d is still recorded as in flight", reserved[0])
+	}
+	if got := reserveNonces(t, s, 2); !slices.Equal(got, []uint64{20, 22}) {
+		t.Errorf("reserved %v after handing 20 back, want 20 again and then 22", got)
+	}
+}
@@ -0,0 +1,198 @@
+package services
+
+import (
+	"bgt_boost/internal/models"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type OperatorBalances struct {
+	Address          string `json:"address"`
+	Balance          string `json:"balance"`
+	UnboostedBalance string `json:"unboostedBalance"`
+	Boosts           string `json:"boosts"`
+	QueuedBoost      string `json:"queuedBoost"`
+	Reserve          string `json:"reserve"`
+}
+
+func (s *boostService) GetOperatorBalances(ctx context.Context, address string) (OperatorBalances, error) {
+	operator, err := (*s.dbRepository).GetOperator(ctx, address)
+	if err != nil {
+		return OperatorBalances{}, err
+	}
+	operatorAddress := common.HexToAddress(operator.Address)
+	balance, err := (*s.ethRepository).GetBalance(ctx, operatorAddress)
+	if err != nil {
+		return OperatorBalances{}, err
+	}
+	unboostedBalance, err := (*s.ethRepository).GetUnboostedBalance(ctx, operatorAddress)
+	if err != nil {
+		return OperatorBalances{}, err
+	}
+	boosts, err := (*s.ethRepository).GetBoosts(ctx, operatorAddress)
+	if err != nil {
+		return OperatorBalances{}, err
+	}
+	queuedBoost, err := (*s.ethRepository).GetQueuedBoost(ctx, operatorAddress)
+	if err != nil {
+		return OperatorBalances{}, err
+	}
+	reserve, err := s.getReserve(ctx, operator)
+	if err != nil {
+		return OperatorBalances{}, err
+	}
+	return OperatorBalances{
+		Address:          operator.Address,
+		Balance:          balance.String(),
+		UnboostedBalance: unboostedBalance.String(),
+		Boosts:           boosts.String(),
+		QueuedBoost:      queuedBoost.String(),
+		Reserve:          reserve.String(),
+	}, nil
+}
+
+func (s *boostService) getOperators(ctx context.Context) (map[common.Address]models.Operator, error) {
+	operators, err := (*s.dbRepository).GetOperators(ctx)
+	if err != nil {
+		return nil, err
+	}
+	byAddress := make(map[common.Address]models.Operator, len(operators))
+	for _, operator := range operators {
+		byAddress[common.HexToAddress(operator.Address)] = operator
+	}
+	return byAddress, nil
+}
+
+func (s *boostService) getOperator(ctx context.Context, address string) (models.Operator, error) {
+	operator, err := (*s.dbRepository).GetOperator(ctx, address)
+	if err == mongo.ErrNoDocuments {
+		return defaultOperator(address), nil
+	}
+	return operator, err
+}
+
+// operatorFor looks up a validator's operator. Validators always reference an existing operator, but an enabled
+// operator without settings is used should one be missing.
+func operatorFor(operators map[common.Address]models.Operator, address string) models.Operator {
+	if operator, ok := operators[common.HexToAddress(address)]; ok {
+		return operator
+	}
+	return defaultOperator(address)
+}
+
+// filterByOperator keeps the validators that belong to one of the operator addresses.
+func filterByOperator(validators []models.Validator, operatorAddresses []common.Address) []models.Validator {
+	selected := make(map[common.Address]bool, len(operatorAddresses))
+	for _, address := range operatorAddresses {
+		selected[address] = true
+	}
+	var filtered []models.Validator
+	for _, validator := range validators {
+		if selected[common.HexToAddress(validator.OperatorAddress)] {
+			filtered = append(filtered, validator)
+		}
+	}
+	return filtered
+}
+
+func defaultOperator(address string) models.Operator {
+	return models.Operator{
+		Address: common.HexToAddress(address).Hex(),
+		Enabled: true,
+	}
+}
+
+// ErrSignerKeyMismatch is returned for an operator whose signer key signs for another address.
+var ErrSignerKeyMismatch = errors.New("signer key does not belong to the operator address")
+
+// SignerAddress resolves a signer key, the address or the secp256k1 public key Web3Signer knows the key by, to the
+// address its signatures come from.
+func SignerAddress(signerKey string) (common.Address, error) {
+	if common.IsHexAddress(signerKey) {
+		return common.HexToAddress(signerKey), nil
+	}
+	key := common.FromHex(signerKey)
+	switch len(key) {
+	case 33:
+		publicKey, err := crypto.DecompressPubkey(key)
+		if err != nil {
+			return common.Address{}, fmt.Errorf("invalid signer key: %w", err)
+		}
+		return crypto.PubkeyToAddress(*publicKey), nil
+	case 64:
+		// Web3Signer lists public keys without the uncompressed point prefix
+		key = append([]byte{4}, key...)
+	}
+	publicKey, err := crypto.UnmarshalPubkey(key)
+	if err != nil {
+		return common.Address{}, errors.New("invalid signer key: expected an address or a public key")
+	}
+	return crypto.PubkeyToAddress(*publicKey), nil
+}
+
+// CheckSignerKey checks that the operator's signer key, when set, signs for the operator address. Nonces, gas
+// estimates and the msg.sender the contract sees all come from that address.
+func CheckSignerKey(operator models.Operator) error {
+	if operator.SignerKey == "" {
+		return nil
+	}
+	address, err := SignerAddress(operator.SignerKey)
+	if err != nil {
+		return err
+	}
+	if address != common.HexToAddress(operator.Address) {
+		return fmt.Errorf("%w: it signs for %s", ErrSignerKeyMismatch, address.Hex())
+	}
+	return nil
+}
+
+func (s *boostService) allocateUnboostedBalance(ctx context.Context, operator models.Operator, validators []models.Validator) ([]*big.Int, error) {
+	unboostedBalance, err := (*s.ethRepository).GetUnboostedBalance(ctx, common.HexToAddress(operator.Address))
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Unboosted balance: %s", unboostedBalance.String())
+
+	reserve, err := s.getReserve(ctx, operator)
+	if err != nil {
+		return nil, err
+	}
+	available := new(big.Int).Sub(unboostedBalance, reserve)
+	if available.Sign() < 0 {
+		available.SetInt64(0)
+	}
+	log.Printf("Reserve: %s, available to boost: %s", reserve.String(), available.String())
+	return allocateBalance(available, validators)
+}
+
+// getReserve returns how much BGT the operator keeps unboosted: the larger of its absolute reserve and its
+// percentage of the operator's BGT balance.
+func (s *boostService) getReserve(ctx context.Context, operator models.Operator) (*big.Int, error) {
+	reserve := big.NewInt(0)
+	if operator.ReserveAmount != "" {
+		amount, ok := big.NewInt(0).SetString(operator.ReserveAmount, 10)
+		if !ok {
+			return nil, errors.New("invalid reserveAmount")
+		}
+		reserve = amount
+	}
+	if operator.ReservePercentage > 0 {
+		balance, err := (*s.ethRepository).GetBalance(ctx, common.HexToAddress(operator.Address))
+		if err != nil {
+			return nil, err
+		}
+		percentage := new(big.Int).Mul(balance, big.NewInt(int64(operator.ReservePercentage)))
+		percentage.Div(percentage, big.NewInt(100))
+		if percentage.Cmp(reserve) > 0 {
+			reserve = percentage
+		}
+	}
+	return reserve, nil
+}
@@ -0,0 +1,267 @@
+package services
+
+import (
+	"bgt_boost/internal/models"
+	"bgt_boost/internal/repository"
+	"bgt_boost/internal/utils"
+	"context"
+	"fmt"
+	"log"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// runState is the chain state shared by every validator processed in one run.
+type runState struct {
+	CurrentBlock       uint64
+	ActivateBoostDelay uint64
+	DropBoostDelay     uint64
+	BaseFee            *big.Int
+	GasTipCap          *big.Int
+}
+
+type PlannedAction struct {
+	Method          string  `json:"method"`
+	Amount          string  `json:"amount"`
+	EstimatedGas    uint64  `json:"estimatedGas"`
+	EstimatedFee    float64 `json:"estimatedFee"`
+	SimulationError string  `json:"simulationError,omitempty"`
+}
+
+type ValidatorPlan struct {
+	Pubkey          string              `json:"pubkey"`
+	OperatorAddress string              `json:"operatorAddress"`
+	Actions         []PlannedAction     `json:"actions"`
+	Deferred        []DeferredAction    `json:"deferred,omitempty"`
+	QueueReset      *QueueResetDecision `json:"queueReset,omitempty"`
+	AtMaxBoost      bool                `json:"atMaxBoost,omitempty"`
+	Disabled        bool                `json:"disabled,omitempty"`
+	Error           string              `json:"error,omitempty"`
+}
+
+type Plan struct {
+	GeneratedAt time.Time       `json:"generatedAt"`
+	BlockNumber uint64          `json:"blockNumber"`
+	GasFeeCap   string          `json:"gasFeeCap"`
+	GasTipCap   string          `json:"gasTipCap"`
+	Validators  []ValidatorPlan `json:"validators"`
+}
+
+func (s *boostService) getRunState(ctx context.Context) (runState, error) {
+	currentBlock, err := (*s.ethRepository).GetLatestBlock(ctx)
+	if err != nil {
+		return runState{}, err
+	}
+	activationDelay, err := (*s.ethRepository).GetActivateBoostDelay(ctx)
+	if err != nil {
+		return runState{}, err
+	}
+	dropDelay, err := (*s.ethRepository).GetDropBoostDelay(ctx)
+	if err != nil {
+		return runState{}, err
+	}
+	baseFee, err := (*s.ethRepository).GetBaseFee(ctx)
+	if err != nil {
+		return runState{}, err
+	}
+	gasTipCap, err := s.suggestTip(ctx)
+	if err != nil {
+		return runState{}, err
+	}
+	return runState{
+		CurrentBlock:       currentBlock,
+		ActivateBoostDelay: activationDelay,
+		DropBoostDelay:     dropDelay,
+		BaseFee:            baseFee,
+		GasTipCap:          gasTipCap,
+	}, nil
+}
+
+// decision is what the engine decided to do for one validator in a run.
+type decision struct {
+	// Actions are the calls to make, in the order they should be sent
+	Actions    []action
+	Deferred   []DeferredAction
+	QueueReset *QueueResetDecision
+	AtMaxBoost bool
+
+	// boostedQueue is the validator's boostedQueue the decision was made on
+	boostedQueue repository.BoostedQueue
+}
+
+func (s *boostService) planValidator(ctx context.Context, operator models.Operator, validator models.Validator, share *big.Int, state runState) (decision, error) {
+	var d decision
+	boostedQueue, err := (*s.ethRepository).GetBoostedQueue(ctx, common.HexToAddress(validator.OperatorAddress), validator.Pubkey)
+	if err != nil {
+		return d, err
+	}
+	d.boostedQueue = boostedQueue
+
+	// Activation goes first: queueing resets the boostedQueue block and would push a ready activation back.
+	activate, err := s.planActivateBoost(validator, boostedQueue, state)
+	if err != nil {
+		return d, err
+	}
+	queue, err := s.planQueueBoost(ctx, validator, share, boostedQueue, &d)
+	if err != nil {
+		return d, err
+	}
+	queue = s.applyQueueResetPolicy(queue, activate, boostedQueue, state, &d)
+	queueDrop, err := s.planQueueDropBoost(ctx, validator)
+	if err != nil {
+		return d, err
+	}
+	drop, err := s.planDropBoost(ctx, validator, state)
+	if err != nil {
+		return d, err
+	}
+
+	for _, a := range []*action{activate, queue, queueDrop, drop} {
+		if a != nil {
+			d.Actions = append(d.Actions, *a)
+		}
+	}
+	s.applyGasGuard(ctx, &d, operator, state)
+	return d, nil
+}
+
+// Plan reports what the next boost run would do, simulating every call from the operator address without signing.
+func (s *boostService) Plan(ctx context.Context) (Plan, error) {
+	validators, err := (*s.dbRepository).GetValidators(ctx)
+	if err != nil {
+		return Plan{GeneratedAt: time.Now()}, err
+	}
+	return s.plan(ctx, validators)
+}
+
+// PlanOperators plans the next boost run for the validators of the given operators only.
+func (s *boostService) PlanOperators(ctx context.Context, operatorAddresses []common.Address) (Plan, error) {
+	validators, err := (*s.dbRepository).GetValidators(ctx)
+	if err != nil {
+		return Plan{GeneratedAt: time.Now()}, err
+	}
+	return s.plan(ctx, filterByOperator(validators, operatorAddresses))
+}
+
+func (s *boostService) plan(ctx context.Context, validators []models.Validator) (Plan, error) {
+	plan := Plan{GeneratedAt: time.Now()}
+	operators, err := s.getOperators(ctx)
+	if err != nil {
+		return plan, err
+	}
+	state, err := s.getRunState(ctx)
+	if err != nil {
+		return plan, err
+	}
+	fees, err := s.suggestFees(ctx)
+	if err != nil {
+		return plan, err
+	}
+	plan.BlockNumber = state.CurrentBlock
+	plan.GasFeeCap = fees.GasFeeCap.String()
+	plan.GasTipCap = fees.GasTipCap.String()
+
+	groups := GroupByOperator(validators)
+	results := make([][]ValidatorPlan, len(groups))
+	forEachConcurrently(len(groups), s.config.BoostConcurrency, func(i int) {
+		results[i] = s.planOperator(ctx, operatorFor(operators, groups[i][0].OperatorAddress), groups[i], state, fees)
+	})
+	for _, result := range results {
+		plan.Validators = append(plan.Validators, result...)
+	}
+	return plan, nil
+}
+
+func (s *boostService) planOperator(ctx context.Context, operator models.Operator, validators []models.Validator, state runState, fees repository.TransactionFees) []ValidatorPlan {
+	plans := make([]ValidatorPlan, len(validators))
+	for i, validator := range validators {
+		plans[i] = ValidatorPlan{
+			Pubkey:          validator.Pubkey,
+			OperatorAddress: validator.OperatorAddress,
+			Disabled:        !operator.Enabled,
+		}
+	}
+	if !operator.Enabled {
+		return plans
+	}
+
+	shares, err := s.allocateUnboostedBalance(ctx, operator, validators)
+	if err != nil {
+		for i := range plans {
+			plans[i].Error = err.Error()
+		}
+		return plans
+	}
+
+	for i, validator := range validators {
+		d, err := s.planValidator(ctx, operator, validator, shares[i], state)
+		if err != nil {
+			plans[i].Error = err.Error()
+			continue
+		}
+		plans[i].AtMaxBoost = d.AtMaxBoost
+		plans[i].Deferred = d.Deferred
+		plans[i].QueueReset = d.QueueReset
+		for _, a := range d.Actions {
+			plans[i].Actions = append(plans[i].Actions, s.simulateAction(ctx, a, fees))
+		}
+	}
+	return plans
+}
+
+// simulateAction runs the action's call and prices its estimated gas at the fee cap a transaction would be sent with.
+func (s *boostService) simulateAction(ctx context.Context, a action, fees repository.TransactionFees) PlannedAction {
+	planned := PlannedAction{
+		Method: a.Method,
+		Amount: a.Amount.String(),
+	}
+	from := common.HexToAddress(a.Validator.OperatorAddress)
+	if err := (*s.ethRepository).SimulateTransaction(ctx, from, s.config.BGTContract.Address, a.Data); err != nil {
+		planned.SimulationError = err.Error()
+		return planned
+	}
+	gas, err := (*s.ethRepository).EstimateGas(ctx, from, s.config.BGTContract.Address, a.Data)
+	if err != nil {
+		planned.SimulationError = err.Error()
+		return planned
+	}
+	planned.EstimatedGas = gas
+	planned.EstimatedFee = utils.ConvertWeiToEther(new(big.Int).Mul(new(big.Int).SetUint64(gas), fees.GasFeeCap))
+	return planned
+}
+
+func (p Plan) Log() {
+	log.Printf("Boost plan at block %d (fee cap %s wei, tip %s wei)", p.BlockNumber, p.GasFeeCap, p.GasTipCap)
+	for _, validator := range p.Validators {
+		if validator.Error != "" {
+			log.Printf("Validator %s (operator %s): error: %s", validator.Pubkey, validator.OperatorAddress, validator.Error)
+			continue
+		}
+		if validator.Disabled {
+			log.Printf("Validator %s (operator %s): operator disabled", validator.Pubkey, validator.OperatorAddress)
+			continue
+		}
+		if validator.AtMaxBoost {
+			log.Printf("Validator %s (operator %s): at max boost", validator.Pubkey, validator.OperatorAddress)
+		}
+		for _, deferred := range validator.Deferred {
+			log.Printf("Validator %s (operator %s): %s %s deferred: %s", validator.Pubkey, validator.OperatorAddress, deferred.Method, deferred.Amount, deferred.Reason)
+		}
+		if len(validator.Actions) == 0 {
+			log.Printf("Validator %s (operator %s): nothing to do", validator.Pubkey, validator.OperatorAddress)
+			continue
+		}
+		actions := make([]string, 0, len(validator.Actions))
+		for _, a := range validator.Actions {
+			if a.SimulationError != "" {
+				actions = append(actions, fmt.Sprintf("%s %s reverts: %s", a.Method, a.Amount, a.SimulationError))
+				continue
+			}
+			actions = append(actions, fmt.Sprintf("%s %s gas %d fee %f", a.Method, a.Amount, a.EstimatedGas, a.EstimatedFee))
+		}
+		log.Printf("Validator %s (operator %s): [%s]", validator.Pubkey, validator.OperatorAddress, strings.Join(actions, ", "))
+	}
+}
@@ -0,0 +1,48 @@
+package services
+
+import (
+	"log"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// forEachConcurrently calls fn for every index in [0, count) on a pool of workers and waits for all of them.
+func forEachConcurrently(count int, workers int, fn func(i int)) {
+	if count == 0 {
+		return
+	}
+	workers = max(1, min(workers, count))
+	jobs := make(chan int, count)
+	for i := 0; i < count; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	log.Printf("Processing %d operators with %d workers", count, workers)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				log.Printf("Operators waiting in queue: %d", len(jobs))
+				fn(i)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// operatorLocks serializes everything that sends transactions from the same operator address, so that runs,
+// workers and admin requests never race for the operator's nonce.
+type operatorLocks struct {
+	locks sync.Map
+}
+
+func (l *operatorLocks) lock(operatorAddress string) func() {
+	value, _ := l.locks.LoadOrStore(common.HexToAddress(operatorAddress), &sync.Mutex{})
+	mutex := value.(*sync.Mutex)
+	mutex.Lock()
+	return mutex.Unlock
+}
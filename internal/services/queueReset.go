@@ -0,0 +1,71 @@
+package services
+
+import (
+	"bgt_boost/internal/repository"
+	"fmt"
+	"log"
+	"math/big"
+)
+
+const (
+	// QueueResetPolicyHold never queues on top of a pending boost that is not ready to activate yet
+	QueueResetPolicyHold = "hold"
+	// QueueResetPolicyReset always queues, restarting the activation delay of the pending boost
+	QueueResetPolicyReset = "reset"
+	// QueueResetPolicyAuto picks whichever choice delays fewer BGT-blocks
+	QueueResetPolicyAuto = "auto"
+)
+
+// QueueResetDecision describes what queueing on top of a pending boost would cost. ResetDelayBlocks is how far the
+// pending amount's activation would be pushed back if queued now, HoldDelayBlocks is how long the new amount would
+// wait for the pending one to activate first.
+type QueueResetDecision struct {
+	PendingAmount    string `json:"pendingAmount"`
+	ResetDelayBlocks uint64 `json:"resetDelayBlocks"`
+	HoldDelayBlocks  uint64 `json:"holdDelayBlocks"`
+	Choice           string `json:"choice"`
+}
+
+// applyQueueResetPolicy decides whether a planned queueBoost may go ahead while the validator has a pending boost
+// that cannot be activated in the same run. Queueing resets the boostedQueue block, so the pending amount would
+// wait the full activation delay again.
+func (s *boostService) applyQueueResetPolicy(queue *action, activate *action, boostedQueue repository.BoostedQueue, state runState, d *decision) *action {
+	if queue == nil || activate != nil || boostedQueue.Balance.Sign() <= 0 {
+		return queue
+	}
+
+	eligibleBlock := boostedQueue.BlockNumber + state.ActivateBoostDelay + 1
+	resetDelay := state.CurrentBlock - boostedQueue.BlockNumber
+	holdDelay := eligibleBlock - state.CurrentBlock
+
+	choice := QueueResetPolicyHold
+	switch s.config.QueueResetPolicy {
+	case QueueResetPolicyReset:
+		choice = QueueResetPolicyReset
+	case QueueResetPolicyAuto:
+		resetCost := new(big.Int).Mul(boostedQueue.Balance, new(big.Int).SetUint64(resetDelay))
+		holdCost := new(big.Int).Mul(queue.Amount, new(big.Int).SetUint64(holdDelay))
+		if resetCost.Cmp(holdCost) < 0 {
+			choice = QueueResetPolicyReset
+		}
+	}
+
+	d.QueueReset = &QueueResetDecision{
+		PendingAmount:    boostedQueue.Balance.String(),
+		ResetDelayBlocks: resetDelay,
+		HoldDelayBlocks:  holdDelay,
+		Choice:           choice,
+	}
+	log.Printf("Pending boost of %s: queueing now delays it by %d blocks, holding delays the new boost by %d blocks, choosing %s",
+		boostedQueue.Balance.String(), resetDelay, holdDelay, choice)
+
+	if choice == QueueResetPolicyReset {
+		return queue
+	}
+	d.Deferred = append(d.Deferred, DeferredAction{
+		Method: queue.Method,
+		Amount: queue.Amount.String(),
+		Reason: fmt.Sprintf("holding until the pending boost activates in %d blocks", holdDelay),
+	})
+	return nil
+}
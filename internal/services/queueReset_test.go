@@ -0,0 +1,85 @@
+package services
+
+import (
+	"bgt_boost/internal/config"
+	"bgt_boost/internal/models"
+	"bgt_boost/internal/repository"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+)
+
+const activateBoostABI = `[{"type": "function", "name": "activateBoost", "inputs": [{"name": "user", "type": "address"}, {"name": "pubkey", "type": "bytes"}], "outputs": [{"name": "", "type": "bool"}]}]`
+
+func TestApplyQueueResetPolicy(t *testing.T) {
+	contractABI, err := abi.JSON(strings.NewReader(activateBoostABI))
+	if err != nil {
+		t.Fatalf("failed to parse ABI: %v", err)
+	}
+	validator := models.Validator{OperatorAddress: testOperator, Pubkey: "0x01"}
+
+	// The pending boost was queued at block 1000 with a delay of 100 blocks, so it is eligible from block 1101
+	const queuedAt, delay = 1000, 100
+	tests := []struct {
+		policy       string
+		currentBlock uint64
+		pending      int64
+		amount       int64
+		wantQueued   bool
+		// wantChoice is empty when activation goes first and there is nothing to decide
+		wantChoice string
+		wantReset  uint64
+		wantHold   uint64
+	}{
+		{QueueResetPolicyHold, 1040, 500, 500, false, QueueResetPolicyHold, 40, 61},
+		{QueueResetPolicyHold, 1100, 500, 500, false, QueueResetPolicyHold, 100, 1},
+		{QueueResetPolicyHold, 1101, 500, 500, true, "", 0, 0},
+		{QueueResetPolicyReset, 1040, 500, 500, true, QueueResetPolicyReset, 40, 61},
+		{QueueResetPolicyReset, 1100, 500, 500, true, QueueResetPolicyReset, 100, 1},
+		{QueueResetPolicyReset, 1101, 500, 500, true, "", 0, 0},
+		// 10 pending BGT losing 40 blocks costs less than 1000 new BGT waiting 61 blocks
+		{QueueResetPolicyAuto, 1040, 10, 1000, true, QueueResetPolicyReset, 40, 61},
+		// 1000 pending BGT losing 40 blocks costs more than 10 new BGT waiting 61 blocks
+		{QueueResetPolicyAuto, 1040, 1000, 10, false, QueueResetPolicyHold, 40, 61},
+		// One block before eligibility holding is almost free
+		{QueueResetPolicyAuto, 1100, 500, 500, false, QueueResetPolicyHold, 100, 1},
+		{QueueResetPolicyAuto, 1101, 1000, 10, true, "", 0, 0},
+	}
+	for _, tt := range tests {
+		s := &boostService{config: &config.Config{
+			QueueResetPolicy: tt.policy,
+			BGTContract:      config.Contract{ABI: contractABI},
+		}}
+		boostedQueue := repository.BoostedQueue{Balance: big.NewInt(tt.pending), BlockNumber: queuedAt}
+		state := runState{CurrentBlock: tt.currentBlock, ActivateBoostDelay: delay}
+		activate, err := s.planActivateBoost(validator, boostedQueue, state)
+		if err != nil {
+			t.Fatalf("planActivateBoost() error: %v", err)
+		}
+
+		var d decision
+		queue := &action{Method: methodQueueBoost, Validator: validator, Amount: big.NewInt(tt.amount)}
+		got := s.applyQueueResetPolicy(queue, activate, boostedQueue, state, &d)
+
+		if queued := got != nil; queued != tt.wantQueued {
+			t.Errorf("%s at block %d: queued = %v, want %v", tt.policy, tt.currentBlock, queued, tt.wantQueued)
+		}
+		if tt.wantChoice == "" {
+			if activate == nil || d.QueueReset != nil {
+				t.Errorf("%s at block %d: want the activation planned without a reset decision", tt.policy, tt.currentBlock)
+			}
+			continue
+		}
+		if d.QueueReset == nil {
+			t.Fatalf("%s at block %d: no reset decision reported", tt.policy, tt.currentBlock)
+		}
+		if d.QueueReset.Choice != tt.wantChoice || d.QueueReset.ResetDelayBlocks != tt.wantReset || d.QueueReset.HoldDelayBlocks != tt.wantHold {
+			t.Errorf("%s at block %d: got %+v, want %s with reset delay %d and hold delay %d", tt.policy, tt.currentBlock, *d.QueueReset, tt.wantChoice, tt.wantReset, tt.wantHold)
+		}
+		if deferred := len(d.Deferred) == 1; deferred == tt.wantQueued {
+			t.Errorf("%s at block %d: deferred %v, want the queueBoost deferred only when held", tt.policy, tt.currentBlock, d.Deferred)
+		}
+	}
+}
@@ -0,0 +1,245 @@
+package services
+
+import (
+	"bgt_boost/internal/models"
+	"context"
+	"log"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+type ValidatorReconciliation struct {
+	Pubkey          string   `json:"pubkey"`
+	OperatorAddress string   `json:"operatorAddress"`
+	ChainBoosted    string   `json:"chainBoosted"`
+	DbBoosted       string   `json:"dbBoosted"`
+	ChainQueued     string   `json:"chainQueued"`
+	DbQueued        string   `json:"dbQueued"`
+	Discrepancy     bool     `json:"discrepancy"`
+	Corrections     []string `json:"corrections,omitempty"`
+	Error           string   `json:"error,omitempty"`
+}
+
+type OperatorReconciliation struct {
+	Address          string `json:"address"`
+	ChainBoosts      string `json:"chainBoosts"`
+	DbBoosts         string `json:"dbBoosts"`
+	ChainQueuedBoost string `json:"chainQueuedBoost"`
+	DbQueuedBoost    string `json:"dbQueuedBoost"`
+	Discrepancy      bool   `json:"discrepancy"`
+	Error            string `json:"error,omitempty"`
+}
+
+type Reconciliation struct {
+	GeneratedAt time.Time                 `json:"generatedAt"`
+	BlockNumber uint64                    `json:"blockNumber"`
+	Corrected   bool                      `json:"corrected"`
+	Operators   []OperatorReconciliation  `json:"operators"`
+	Validators  []ValidatorReconciliation `json:"validators"`
+}
+
+// Reconcile compares each validator's boosted amount and boostedQueue, and each operator's boosts and queuedBoost,
+// with the totals recorded in the database. With correct set, validators that disagree get records marked with
+// source reconciliation that bring the database totals in line with the contract; the indexer replaces them once it
+// stores the events they stood in for.
+func (s *boostService) Reconcile(ctx context.Context, correct bool) (Reconciliation, error) {
+	reconciliation := Reconciliation{GeneratedAt: time.Now(), Corrected: correct}
+	validators, err := (*s.dbRepository).GetValidators(ctx)
+	if err != nil {
+		return reconciliation, err
+	}
+	currentBlock, err := (*s.ethRepository).GetLatestBlock(ctx)
+	if err != nil {
+		return reconciliation, err
+	}
+	reconciliation.BlockNumber = currentBlock
+
+	groups := GroupByOperator(validators)
+	operators := make([]OperatorReconciliation, len(groups))
+	results := make([][]ValidatorReconciliation, len(groups))
+	forEachConcurrently(len(groups), s.config.BoostConcurrency, func(i int) {
+		operators[i], results[i] = s.reconcileOperator(ctx, groups[i], currentBlock, correct)
+	})
+	reconciliation.Operators = operators
+	for _, result := range results {
+		reconciliation.Validators = append(reconciliation.Validators, result...)
+	}
+	return reconciliation, nil
+}
+
+// reconcileOperator holds the operator's lock when correcting, so that a transaction that is mined but not yet
+// recorded is never mistaken for drift. A report alone does not wait for running boosts.
+func (s *boostService) reconcileOperator(ctx context.Context, validators []models.Validator, currentBlock uint64, correct bool) (OperatorReconciliation, []ValidatorReconciliation) {
+	operatorAddress := common.HexToAddress(validators[0].OperatorAddress)
+	operator := OperatorReconciliation{Address: operatorAddress.Hex()}
+	if correct {
+		unlock := s.operatorLocks.lock(operator.Address)
+		defer unlock()
+	}
+
+	results := make([]ValidatorReconciliation, len(validators))
+	dbBoosts := big.NewInt(0)
+	dbQueuedBoost := big.NewInt(0)
+	for i, validator := range validators {
+		results[i] = ValidatorReconciliation{
+			Pubkey:          validator.Pubkey,
+			OperatorAddress: validator.OperatorAddress,
+		}
+		dbBoosted, dbQueued, err := s.reconcileValidator(ctx, validator, currentBlock, correct, &results[i])
+		if err != nil {
+			log.Printf("Failed to reconcile validator %s: %v", validator.Pubkey, err)
+			results[i].Error = err.Error()
+			continue
+		}
+		dbBoosts.Add(dbBoosts, dbBoosted)
+		dbQueuedBoost.Add(dbQueuedBoost, dbQueued)
+	}
+	operator.DbBoosts = dbBoosts.String()
+	operator.DbQueuedBoost = dbQueuedBoost.String()
+
+	chainBoosts, err := (*s.ethRepository).GetBoosts(ctx, operatorAddress)
+	if err != nil {
+		operator.Error = err.Error()
+		return operator, results
+	}
+	chainQueuedBoost, err := (*s.ethRepository).GetQueuedBoost(ctx, operatorAddress)
+	if err != nil {
+		operator.Error = err.Error()
+		return operator, results
+	}
+	operator.ChainBoosts = chainBoosts.String()
+	operator.ChainQueuedBoost = chainQueuedBoost.String()
+	// Boosts on validators this tool does not manage also show up here, and cannot be corrected per validator
+	operator.Discrepancy = chainBoosts.Cmp(dbBoosts) != 0 || chainQueuedBoost.Cmp(dbQueuedBoost) != 0
+	return operator, results
+}
+
+// reconcileValidator fills in the validator's result and returns its database totals after any correction.
+func (s *boostService) reconcileValidator(ctx context.Context, validator models.Validator, currentBlock uint64, correct bool, result *ValidatorReconciliation) (*big.Int, *big.Int, error) {
+	operatorAddress := common.HexToAddress(validator.OperatorAddress)
+	chainBoosted, err := (*s.ethRepository).GetBoosted(ctx, operatorAddress, validator.Pubkey)
+	if err != nil {
+		return nil, nil, err
+	}
+	boostedQueue, err := (*s.ethRepository).GetBoostedQueue(ctx, operatorAddress, validator.Pubkey)
+	if err != nil {
+		return nil, nil, err
+	}
+	totals, err := (*s.dbRepository).GetBoostTotals(ctx, validator.Pubkey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Activations move the whole queue into boosted, cancels take from the queue and drops take from boosted
+	dbBoosted := new(big.Int).Sub(totals.Activated, totals.Dropped)
+	dbQueued := new(big.Int).Sub(totals.Queued, totals.Activated)
+	dbQueued.Sub(dbQueued, totals.Cancelled)
+
+	result.ChainBoosted = chainBoosted.String()
+	result.DbBoosted = dbBoosted.String()
+	result.ChainQueued = boostedQueue.Balance.String()
+	result.DbQueued = dbQueued.String()
+	result.Discrepancy = chainBoosted.Cmp(dbBoosted) != 0 || boostedQueue.Balance.Cmp(dbQueued) != 0
+	if !result.Discrepancy || !correct {
+		return dbBoosted, dbQueued, nil
+	}
+
+	timestamp, err := (*s.ethRepository).GetBlockTimestamp(ctx, currentBlock)
+	if err != nil {
+		return nil, nil, err
+	}
+	// A correcting activation also takes from the queue, so boosted is corrected first and the queue afterwards
+	boostedDiff := new(big.Int).Sub(chainBoosted, dbBoosted)
+	switch boostedDiff.Sign() {
+	case 1:
+		err = (*s.dbRepository).AddActivateBoost(ctx, models.ActivateBoost{
+			Amount:          boostedDiff.String(),
+			ValidatorPubkey: validator.Pubkey,
+			OperatorAddress: validator.OperatorAddress,
+			BlockNumber:     currentBlock,
+			BlockTimestamp:  timestamp,
+			TransactionFrom: validator.OperatorAddress,
+			ToContract:      s.config.BGTContract.Address.Hex(),
+			Source:          models.SourceReconciliation,
+		})
+		dbQueued.Sub(dbQueued, boostedDiff)
+		result.Corrections = append(result.Corrections, "activateBoost "+boostedDiff.String())
+	case -1:
+		boostedDiff.Neg(boostedDiff)
+		err = (*s.dbRepository).AddDropBoost(ctx, models.DropBoost{
+			Amount:          boostedDiff.String(),
+			ValidatorPubkey: validator.Pubkey,
+			OperatorAddress: validator.OperatorAddress,
+			BlockNumber:     currentBlock,
+			BlockTimestamp:  timestamp,
+			TransactionFrom: validator.OperatorAddress,
+			ToContract:      s.config.BGTContract.Address.Hex(),
+			Source:          models.SourceReconciliation,
+		})
+		result.Corrections = append(result.Corrections, "dropBoost "+boostedDiff.String())
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	queuedDiff := new(big.Int).Sub(boostedQueue.Balance, dbQueued)
+	switch queuedDiff.Sign() {
+	case 1:
+		err = (*s.dbRepository).AddQueueBoost(ctx, models.QueueBoost{
+			ValidatorPubkey: validator.Pubkey,
+			OperatorAddress: validator.OperatorAddress,
+			BlockNumber:     currentBlock,
+			Amount:          queuedDiff.String(),
+			BlockTimestamp:  timestamp,
+			TransactionFrom: validator.OperatorAddress,
+			ToContract:      s.config.BGTContract.Address.Hex(),
+			Source:          models.SourceReconciliation,
+			Status:          models.QueueBoostStatusPending,
+		})
+		result.Corrections = append(result.Corrections, "queueBoost "+queuedDiff.String())
+	case -1:
+		queuedDiff.Neg(queuedDiff)
+		err = (*s.dbRepository).AddCancelBoost(ctx, models.CancelBoost{
+			Amount:          queuedDiff.String(),
+			ValidatorPubkey: validator.Pubkey,
+			OperatorAddress: validator.OperatorAddress,
+			BlockNumber:     currentBlock,
+			BlockTimestamp:  timestamp,
+			TransactionFrom: validator.OperatorAddress,
+			ToContract:      s.config.BGTContract.Address.Hex(),
+			Source:          models.SourceReconciliation,
+		})
+		result.Corrections = append(result.Corrections, "cancelBoost "+queuedDiff.String())
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	log.Printf("Reconciled validator %s: %v", validator.Pubkey, result.Corrections)
+	return chainBoosted, boostedQueue.Balance, nil
+}
+
+func (r Reconciliation) Log() {
+	for _, validator := range r.Validators {
+		if validator.Error != "" {
+			log.Printf("Validator %s (operator %s): error: %s", validator.Pubkey, validator.OperatorAddress, validator.Error)
+			continue
+		}
+		if validator.Discrepancy {
+			log.Printf("Validator %s (operator %s): boosted %s on chain, %s recorded; queued %s on chain, %s recorded; corrections %v",
+				validator.Pubkey, validator.OperatorAddress, validator.ChainBoosted, validator.DbBoosted, validator.ChainQueued, validator.DbQueued, validator.Corrections)
+		}
+	}
+	for _, operator := range r.Operators {
+		if operator.Error != "" {
+			log.Printf("Operator %s: error: %s", operator.Address, operator.Error)
+			continue
+		}
+		if operator.Discrepancy {
+			log.Printf("Operator %s: boosts %s on chain, %s recorded; queued boost %s on chain, %s recorded",
+				operator.Address, operator.ChainBoosts, operator.DbBoosts, operator.ChainQueuedBoost, operator.DbQueuedBoost)
+		}
+	}
+	log.Printf("Reconciliation at block %d checked %d validators", r.BlockNumber, len(r.Validators))
+}
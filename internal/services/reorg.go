@@ -0,0 +1,101 @@
+package services
+
+import (
+	"bgt_boost/internal/models"
+	"context"
+	"log"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// VerifyTransactions checks that the recorded transactions mined in the last REORG_CHECK_BLOCKS blocks are still in
+// the canonical chain. A transaction a reorg mined again in another block has its records moved to that block. One
+// the reorg dropped, which every RPC endpoint that has reached its block agrees on, has its records undone and is
+// reopened in the journal, broadcast again and waited for, so that it is recorded once it is mined again or replaced
+// when it stays stuck.
+func (s *boostService) VerifyTransactions(ctx context.Context) error {
+	latestBlock, err := (*s.ethRepository).GetLatestBlock(ctx)
+	if err != nil {
+		return err
+	}
+	fromBlock := uint64(0)
+	if latestBlock > uint64(s.config.ReorgCheckBlocks) {
+		fromBlock = latestBlock - uint64(s.config.ReorgCheckBlocks)
+	}
+	transactions, err := (*s.dbRepository).GetMinedTransactions(ctx, fromBlock)
+	if err != nil {
+		return err
+	}
+
+	var moved, reopened int
+	for _, transaction := range transactions {
+		inclusion, err := (*s.ethRepository).GetTransactionInclusion(ctx, common.HexToHash(transaction.TransactionHash))
+		if err != nil {
+			log.Printf("Failed to verify transaction %s: %v", transaction.TransactionHash, err)
+			continue
+		}
+		if inclusion != nil && inclusion.BlockHash == transaction.BlockHash {
+			continue
+		}
+		if inclusion != nil {
+			log.Printf("Transaction %s moved from block %d to %d by a reorg", transaction.TransactionHash, transaction.BlockNumber, inclusion.BlockNumber)
+			if err := (*s.dbRepository).MoveTransaction(ctx, transaction.TransactionHash, *inclusion); err != nil {
+				log.Printf("Failed to move transaction %s: %v", transaction.TransactionHash, err)
+				continue
+			}
+			moved++
+			continue
+		}
+
+		// A single endpoint that lags or lost the receipt must not undo records
+		dropped, err := (*s.ethRepository).IsTransactionDropped(ctx, common.HexToHash(transaction.TransactionHash), transaction.BlockNumber)
+		if err != nil {
+			log.Printf("Failed to verify transaction %s: %v", transaction.TransactionHash, err)
+			continue
+		}
+		if !dropped {
+			continue
+		}
+		log.Printf("Transaction %s of block %d was dropped by a reorg, reopening it", transaction.TransactionHash, transaction.BlockNumber)
+		if err := s.reopenTransaction(ctx, transaction); err != nil {
+			log.Printf("Failed to reopen transaction %s: %v", transaction.TransactionHash, err)
+			continue
+		}
+		reopened++
+	}
+	log.Printf("Verified %d transactions: %d moved, %d reopened", len(transactions), moved, reopened)
+	return nil
+}
+
+// reopenTransaction undoes the records of a dropped transaction and resumes its journal group, which broadcasts it
+// again and waits for it, or one of its replacements, to be mined. The operator's lock is held from before the
+// records are undone, so no run plans on them in between.
+func (s *boostService) reopenTransaction(ctx context.Context, transaction models.Transaction) error {
+	operator, err := s.getOperator(ctx, transaction.OperatorAddress)
+	if err != nil {
+		return err
+	}
+	unlock := s.operatorLocks.lock(operator.Address)
+	defer unlock()
+
+	if err := (*s.dbRepository).ReopenTransaction(ctx, transaction.TransactionHash); err != nil {
+		return err
+	}
+	s.nonces.invalidate(common.HexToAddress(transaction.OperatorAddress))
+
+	signedTx := new(types.Transaction)
+	if err := signedTx.UnmarshalBinary(common.FromHex(transaction.SignedTransaction)); err != nil {
+		return err
+	}
+	// The transaction is usually back in the mempool after the reorg, but nodes that never saw it need it again
+	if err := (*s.ethRepository).BroadcastTransaction(ctx, signedTx); err != nil {
+		log.Printf("Failed to broadcast transaction %s again: %v", transaction.TransactionHash, err)
+	}
+
+	group, err := (*s.dbRepository).GetTransactionGroup(ctx, transaction.GroupID)
+	if err != nil {
+		return err
+	}
+	return s.resumeGroup(ctx, operator, group)
+}
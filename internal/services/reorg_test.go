@@ -0,0 +1,95 @@
+package services
+
+import (
+	"bgt_boost/internal/config"
+	"bgt_boost/internal/models"
+	"bgt_boost/internal/repository"
+	"context"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const testOperator = "0x1111111111111111111111111111111111111111"
+
+func minedTransaction(t *testing.T, nonce uint64, blockNumber uint64, blockHash string) models.Transaction {
+	t.Helper()
+	tx := testTransaction(nonce, 2000, 100)
+	signed, err := tx.MarshalBinary()
+	if err != nil {
+		t.Fatalf("failed to encode transaction: %v", err)
+	}
+	id := primitive.NewObjectID()
+	return models.Transaction{
+		ID:                id,
+		GroupID:           id,
+		OperatorAddress:   testOperator,
+		Nonce:             nonce,
+		SignedTransaction: hexutil.Encode(signed),
+		TransactionHash:   tx.Hash().Hex(),
+		State:             models.TransactionStateMined,
+		Recorded:          true,
+		BlockNumber:       blockNumber,
+		BlockHash:         blockHash,
+	}
+}
+
+func TestVerifyTransactions(t *testing.T) {
+	canonical := minedTransaction(t, 1, 950, "0xaaa")
+	moved := minedTransaction(t, 2, 960, "0xbbb")
+	dropped := minedTransaction(t, 3, 970, "0xccc")
+	// Missing from the endpoint that was asked, but still found by another one
+	lagging := minedTransaction(t, 4, 980, "0xddd")
+
+	db := newFakeDb()
+	db.transactions = []models.Transaction{canonical, moved, dropped, lagging}
+	eth := &fakeEth{
+		latestBlock: 1000,
+		inclusions: map[common.Hash]*repository.TransactionInclusion{
+			common.HexToHash(canonical.TransactionHash): {BlockNumber: 950, BlockHash: "0xaaa"},
+			common.HexToHash(moved.TransactionHash):     {BlockNumber: 962, BlockHash: "0xeee"},
+		},
+		dropped: map[common.Hash]bool{common.HexToHash(dropped.TransactionHash): true},
+		waitResults: []waitResult{
+			{info: repository.TransactionInfo{TransactionHash: dropped.TransactionHash, BlockNumber: 990}},
+		},
+	}
+	s := newTestService(&config.Config{ReorgCheckBlocks: 100, InclusionTimeoutBlocks: 20}, db, eth)
+
+	if err := s.VerifyTransactions(context.Background()); err != nil {
+		t.Fatalf("VerifyTransactions() error: %v", err)
+	}
+
+	if len(db.moved) != 1 || db.moved[moved.TransactionHash].BlockNumber != 962 {
+		t.Errorf("moved = %v, want only %s moved to block 962", db.moved, moved.TransactionHash)
+	}
+	if len(db.reopened) != 1 || db.reopened[0] != dropped.TransactionHash {
+		t.Fatalf("reopened = %v, want only %s", db.reopened, dropped.TransactionHash)
+	}
+	if len(eth.broadcast) != 1 || eth.broadcast[0] != common.HexToHash(dropped.TransactionHash) {
+		t.Errorf("broadcast = %v, want the dropped transaction broadcast again", eth.broadcast)
+	}
+	if state := db.finished[dropped.GroupID]; state != models.TransactionStateMined {
+		t.Errorf("dropped transaction finished as %q, want it mined again", state)
+	}
+	if len(db.recorded) != 1 || db.recorded[0] != dropped.TransactionHash {
+		t.Errorf("recorded = %v, want the dropped transaction recorded again", db.recorded)
+	}
+}
+
+func TestVerifyTransactionsSkipsOlderBlocks(t *testing.T) {
+	old := minedTransaction(t, 1, 850, "0xaaa")
+	db := newFakeDb()
+	db.transactions = []models.Transaction{old}
+	eth := &fakeEth{latestBlock: 1000, dropped: map[common.Hash]bool{common.HexToHash(old.TransactionHash): true}}
+	s := newTestService(&config.Config{ReorgCheckBlocks: 100}, db, eth)
+
+	if err := s.VerifyTransactions(context.Background()); err != nil {
+		t.Fatalf("VerifyTransactions() error: %v", err)
+	}
+	if len(db.reopened) != 0 {
+		t.Errorf("reopened %v, a transaction older than REORG_CHECK_BLOCKS", db.reopened)
+	}
+}
@@ -0,0 +1,163 @@
+package services
+
+import (
+	"bgt_boost/internal/models"
+	"bgt_boost/internal/repository"
+	"bgt_boost/internal/utils"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// ErrTransactionStuck is returned when none of the transactions sent for a nonce is mined after
+// MAX_INCLUSION_TIMEOUTS inclusion timeouts. They stay broadcast and are waited for again on the next start.
+var ErrTransactionStuck = errors.New("transaction not mined after the maximum number of inclusion timeouts")
+
+// ErrNonceConsumed is returned when the operator's nonce moved past the pending transaction's nonce without any of
+// the transactions sent for it being mined, so another transaction used the nonce.
+var ErrNonceConsumed = errors.New("nonce used by another transaction")
+
+// waitForTransaction waits for the pending transaction, replacing it with bumped fees each time it times out.
+func (s *boostService) waitForTransaction(ctx context.Context, operator models.Operator, pending *pendingTransaction) (repository.TransactionInfo, error) {
+	from := common.HexToAddress(operator.Address)
+	for timeouts := 1; ; timeouts++ {
+		txInfo, err := (*s.ethRepository).WaitForTransaction(ctx, pending.transactionHashes, uint64(s.config.InclusionTimeoutBlocks))
+		if !errors.Is(err, repository.ErrInclusionTimeout) {
+			if len(pending.replacement.ReplacementTransactionHashes) > 0 && txInfo.TransactionHash != "" {
+				pending.replacement.MinedTransactionHash = txInfo.TransactionHash
+				s.saveTransactionReplacement(ctx, pending.replacement)
+			}
+			return txInfo, err
+		}
+
+		latestTx := pending.signedTx
+		consumed, err := s.nonceConsumed(ctx, from, pending)
+		if err != nil {
+			log.Printf("Failed to check the nonce of transaction %s: %v", latestTx.Hash().Hex(), err)
+		}
+		if consumed {
+			return repository.TransactionInfo{}, fmt.Errorf("%w: nonce %d of operator %s", ErrNonceConsumed, latestTx.Nonce(), operator.Address)
+		}
+		if s.config.MaxInclusionTimeouts > 0 && timeouts >= s.config.MaxInclusionTimeouts {
+			return repository.TransactionInfo{}, fmt.Errorf("%w: transaction %s after %d blocks", ErrTransactionStuck, latestTx.Hash().Hex(), timeouts*s.config.InclusionTimeoutBlocks)
+		}
+
+		fees, ok, err := s.bumpFees(ctx, latestTx)
+		if err != nil {
+			log.Printf("Failed to price replacement for transaction %s: %v", latestTx.Hash().Hex(), err)
+			continue
+		}
+		if !ok {
+			log.Printf("Transaction %s is not mined after %d blocks and its fee cap is at the %.4f gwei ceiling, still waiting", latestTx.Hash().Hex(), s.config.InclusionTimeoutBlocks, s.config.MaxFeeCapGwei)
+			continue
+		}
+		tx := withFees(latestTx, fees)
+		journal, err := s.journalTransaction(ctx, from, tx, pending.journal.Intent, pending.journal.GroupID)
+		if err != nil {
+			log.Printf("Failed to journal replacement for transaction %s: %v", latestTx.Hash().Hex(), err)
+			continue
+		}
+		replacementTx, err := s.signJournaled(ctx, operator, &journal, tx)
+		if err != nil {
+			log.Printf("Failed to sign replacement for transaction %s: %v", latestTx.Hash().Hex(), err)
+			continue
+		}
+		// The broadcast fails when one of the earlier transactions was mined in the meantime, which the next wait finds
+		if err := (*s.ethRepository).BroadcastTransaction(ctx, replacementTx); err != nil {
+			s.markJournaled(ctx, journal.ID, models.TransactionStateFailed, err)
+			log.Printf("Failed to send replacement for transaction %s: %v", latestTx.Hash().Hex(), err)
+			continue
+		}
+		s.markJournaled(ctx, journal.ID, models.TransactionStateBroadcast, nil)
+		s.markJournaled(ctx, pending.journal.ID, models.TransactionStateReplaced, nil)
+		log.Printf("Transaction %s is not mined after %d blocks, replaced by %s with fee cap %s and tip %s", latestTx.Hash().Hex(), s.config.InclusionTimeoutBlocks, replacementTx.Hash().Hex(), fees.GasFeeCap, fees.GasTipCap)
+
+		pending.replaceWith(journal, replacementTx)
+		s.saveTransactionReplacement(ctx, pending.replacement)
+		s.trackNonce(ctx, from, replacementTx.Nonce(), replacementTx.Hash())
+	}
+}
+
+// nonceConsumed reports whether the operator's confirmed nonce is past the pending transaction's nonce while none of
+// the transactions sent for it was mined.
+func (s *boostService) nonceConsumed(ctx context.Context, from common.Address, pending *pendingTransaction) (bool, error) {
+	confirmed, _, err := (*s.ethRepository).GetNonces(ctx, from)
+	if err != nil {
+		return false, err
+	}
+	if confirmed <= pending.signedTx.Nonce() {
+		return false, nil
+	}
+	for _, transactionHash := range pending.transactionHashes {
+		inclusion, err := (*s.ethRepository).GetTransactionInclusion(ctx, transactionHash)
+		if err != nil {
+			return false, err
+		}
+		// Mined just now, which the next wait picks up
+		if inclusion != nil {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// bumpFees raises both fee caps of the transaction by FEE_BUMP_PERCENT, or to the current fees when those are higher.
+// It reports false when the fee cap ceiling leaves no room for a bump the RPC would accept as a replacement.
+func (s *boostService) bumpFees(ctx context.Context, tx *types.Transaction) (repository.TransactionFees, bool, error) {
+	current, err := s.suggestFees(ctx)
+	if err != nil {
+		return repository.TransactionFees{}, false, err
+	}
+	minFeeCap := bumpByPercent(tx.GasFeeCap(), s.config.FeeBumpPercent)
+	minTip := bumpByPercent(tx.GasTipCap(), s.config.FeeBumpPercent)
+	fees := repository.TransactionFees{
+		GasFeeCap: maxBig(minFeeCap, current.GasFeeCap),
+		GasTipCap: maxBig(minTip, current.GasTipCap),
+	}
+	if s.config.MaxFeeCapGwei > 0 {
+		if ceiling := utils.ConvertGweiToWei(s.config.MaxFeeCapGwei); fees.GasFeeCap.Cmp(ceiling) > 0 {
+			fees.GasFeeCap = ceiling
+		}
+	}
+	if fees.GasTipCap.Cmp(fees.GasFeeCap) > 0 {
+		fees.GasTipCap = fees.GasFeeCap
+	}
+	ok := fees.GasFeeCap.Cmp(minFeeCap) >= 0 && fees.GasTipCap.Cmp(minTip) >= 0
+	return fees, ok, nil
+}
+
+// withFees copies the unsigned parts of the transaction with new fee caps.
+func withFees(tx *types.Transaction, fees repository.TransactionFees) *types.Transaction {
+	return types.NewTx(&types.DynamicFeeTx{
+		Nonce:     tx.Nonce(),
+		GasTipCap: fees.GasTipCap,
+		GasFeeCap: fees.GasFeeCap,
+		Gas:       tx.Gas(),
+		To:        tx.To(),
+		Value:     tx.Value(),
+		Data:      tx.Data(),
+	})
+}
+
+func (s *boostService) saveTransactionReplacement(ctx context.Context, replacement models.TransactionReplacement) {
+	if err := (*s.dbRepository).SaveTransactionReplacement(ctx, replacement); err != nil {
+		log.Printf("Failed to record replacement of transaction %s: %v", replacement.OriginalTransactionHash, err)
+	}
+}
+
+func bumpByPercent(value *big.Int, percent int) *big.Int {
+	bumped := new(big.Int).Mul(value, big.NewInt(int64(100+percent)))
+	return bumped.Div(bumped, big.NewInt(100))
+}
+
+func maxBig(a *big.Int, b *big.Int) *big.Int {
+	if a.Cmp(b) >= 0 {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,140 @@
+package services
+
+import (
+	"bgt_boost/internal/config"
+	"bgt_boost/internal/models"
+	"bgt_boost/internal/repository"
+	"bgt_boost/internal/utils"
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+func TestBumpByPercent(t *testing.T) {
+	fee := big.NewInt(1_000_000_000)
+	if got := bumpByPercent(fee, 15); got.Cmp(big.NewInt(1_150_000_000)) != 0 {
+		t.Errorf("bumpByPercent(1 gwei, 15) = %s, want 1150000000", got)
+	}
+	if fee.Cmp(big.NewInt(1_000_000_000)) != 0 {
+		t.Errorf("bumpByPercent changed its input to %s", fee)
+	}
+	if got := bumpByPercent(big.NewInt(10), 15); got.Cmp(big.NewInt(11)) != 0 {
+		t.Errorf("bumpByPercent(10, 15) = %s, want 11 after rounding down", got)
+	}
+	if got := bumpByPercent(big.NewInt(7), 0); got.Cmp(big.NewInt(7)) != 0 {
+		t.Errorf("bumpByPercent(7, 0) = %s, want 7", got)
+	}
+}
+
+func gwei(value float64) *big.Int {
+	return utils.ConvertGweiToWei(value)
+}
+
+func TestBumpFees(t *testing.T) {
+	tests := []struct {
+		name     string
+		ceiling  float64
+		baseFee  float64
+		tip      float64
+		wantCap  *big.Int
+		wantTip  *big.Int
+		wantBump bool
+	}{
+		{"bumped by the percentage", 0, 2, 1, gwei(11.5), gwei(1.15), true},
+		{"current fees when higher", 0, 10, 2, gwei(22), gwei(2), true},
+		{"fee cap at the ceiling", 11, 2, 1, gwei(11), gwei(1.15), false},
+		{"tip capped to the fee cap", 12, 1, 13, gwei(12), gwei(12), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			eth := &fakeEth{baseFee: gwei(tt.baseFee), tip: gwei(tt.tip)}
+			s := newTestService(&config.Config{
+				TipSource:         TipSourceSuggest,
+				BaseFeeMultiplier: 2,
+				FeeBumpPercent:    15,
+				MaxFeeCapGwei:     tt.ceiling,
+			}, newFakeDb(), eth)
+			tx := types.NewTx(&types.DynamicFeeTx{Nonce: 1, GasFeeCap: gwei(10), GasTipCap: gwei(1)})
+
+			fees, ok, err := s.bumpFees(context.Background(), tx)
+			if err != nil {
+				t.Fatalf("bumpFees() error: %v", err)
+			}
+			if ok != tt.wantBump {
+				t.Errorf("bumpFees() ok = %v, want %v", ok, tt.wantBump)
+			}
+			if fees.GasFeeCap.Cmp(tt.wantCap) != 0 || fees.GasTipCap.Cmp(tt.wantTip) != 0 {
+				t.Errorf("bumpFees() = fee cap %s and tip %s, want %s and %s", fees.GasFeeCap, fees.GasTipCap, tt.wantCap, tt.wantTip)
+			}
+		})
+	}
+}
+
+// stuckTransaction is a transaction with nonce 7 that is never mined, whose fee cap is already at the ceiling so that
+// no replacement is signed.
+func stuckTransaction(maxInclusionTimeouts int, eth *fakeEth) (*boostService, *pendingTransaction) {
+	eth.baseFee, eth.tip = gwei(1), gwei(1)
+	s := newTestService(&config.Config{
+		TipSource:              TipSourceSuggest,
+		BaseFeeMultiplier:      2,
+		FeeBumpPercent:         15,
+		MaxFeeCapGwei:          10,
+		InclusionTimeoutBlocks: 20,
+		MaxInclusionTimeouts:   maxInclusionTimeouts,
+	}, newFakeDb(), eth)
+	tx := types.NewTx(&types.DynamicFeeTx{Nonce: 7, GasFeeCap: gwei(10), GasTipCap: gwei(1)})
+	return s, newPendingTransaction(models.Transaction{OperatorAddress: testOperator, Nonce: 7}, tx)
+}
+
+func TestWaitForTransactionGivesUpAfterMaxInclusionTimeouts(t *testing.T) {
+	eth := &fakeEth{confirmed: 7}
+	s, pending := stuckTransaction(3, eth)
+
+	_, err := s.waitForTransaction(context.Background(), defaultOperator(testOperator), pending)
+	if !errors.Is(err, ErrTransactionStuck) {
+		t.Fatalf("waitForTransaction() error = %v, want %v", err, ErrTransactionStuck)
+	}
+	if eth.waits != 3 {
+		t.Errorf("waited %d times, want 3", eth.waits)
+	}
+}
+
+func TestWaitForTransactionStopsWhenNonceIsConsumed(t *testing.T) {
+	// The confirmed nonce moved past 7 while none of the transactions sent for it was mined
+	eth := &fakeEth{confirmed: 8}
+	s, pending := stuckTransaction(0, eth)
+
+	_, err := s.waitForTransaction(context.Background(), defaultOperator(testOperator), pending)
+	if !errors.Is(err, ErrNonceConsumed) {
+		t.Fatalf("waitForTransaction() error = %v, want %v", err, ErrNonceConsumed)
+	}
+	if eth.waits != 1 {
+		t.Errorf("waited %d times, want 1", eth.waits)
+	}
+}
+
+func TestNonceConsumed(t *testing.T) {
+	ctx := context.Background()
+	from := common.HexToAddress(testOperator)
+
+	eth := &fakeEth{confirmed: 7}
+	s, pending := stuckTransaction(0, eth)
+	if consumed, err := s.nonceConsumed(ctx, from, pending); err != nil || consumed {
+		t.Errorf("nonceConsumed() = %v, %v while the nonce is unused", consumed, err)
+	}
+
+	eth.confirmed = 8
+	if consumed, err := s.nonceConsumed(ctx, from, pending); err != nil || !consumed {
+		t.Errorf("nonceConsumed() = %v, %v once another transaction used the nonce", consumed, err)
+	}
+
+	// Mined just before the nonce was checked, so the next wait finds it
+	eth.inclusions = map[common.Hash]*repository.TransactionInclusion{pending.signedTx.Hash(): {BlockNumber: 100}}
+	if consumed, err := s.nonceConsumed(ctx, from, pending); err != nil || consumed {
+		t.Errorf("nonceConsumed() = %v, %v for a transaction that was mined", consumed, err)
+	}
+}
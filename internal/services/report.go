@@ -0,0 +1,138 @@
+package services
+
+import (
+	"bgt_boost/internal/repository"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+type ValidatorStatus string
+
+const (
+	ValidatorStatusQueued     ValidatorStatus = "queued"
+	ValidatorStatusActivated  ValidatorStatus = "activated"
+	ValidatorStatusDropQueued ValidatorStatus = "drop_queued"
+	ValidatorStatusDropped    ValidatorStatus = "dropped"
+	ValidatorStatusSkipped    ValidatorStatus = "skipped"
+	ValidatorStatusDeferred   ValidatorStatus = "deferred"
+	ValidatorStatusDisabled   ValidatorStatus = "disabled"
+	ValidatorStatusReverted   ValidatorStatus = "reverted"
+	ValidatorStatusFailed     ValidatorStatus = "failed"
+)
+
+type ActionReport struct {
+	Method          string `json:"method"`
+	Amount          string `json:"amount"`
+	TransactionHash string `json:"transactionHash"`
+}
+
+type ValidatorReport struct {
+	Pubkey          string              `json:"pubkey"`
+	OperatorAddress string              `json:"operatorAddress"`
+	Status          ValidatorStatus     `json:"status"`
+	Actions         []ActionReport      `json:"actions"`
+	Deferred        []DeferredAction    `json:"deferred,omitempty"`
+	QueueReset      *QueueResetDecision `json:"queueReset,omitempty"`
+	AtMaxBoost      bool                `json:"atMaxBoost,omitempty"`
+	Error           string              `json:"error,omitempty"`
+}
+
+type RunReport struct {
+	StartedAt  time.Time         `json:"startedAt"`
+	FinishedAt time.Time         `json:"finishedAt"`
+	Validators []ValidatorReport `json:"validators"`
+}
+
+func (r *ValidatorReport) addAction(status ValidatorStatus, method string, amount string, transactionHash string) {
+	r.Status = status
+	r.Actions = append(r.Actions, ActionReport{
+		Method:          method,
+		Amount:          amount,
+		TransactionHash: transactionHash,
+	})
+}
+
+// addDeferred records actions held back until a later run. The validator is reported as deferred unless another action
+// was sent for it.
+func (r *ValidatorReport) addDeferred(deferred []DeferredAction) {
+	if len(deferred) == 0 {
+		return
+	}
+	r.Deferred = append(r.Deferred, deferred...)
+	if r.Status == ValidatorStatusSkipped {
+		r.Status = ValidatorStatusDeferred
+	}
+}
+
+// fail records the error that stopped the validator. Calls that failed gas estimation were never signed and are
+// reported as reverted.
+func (r *ValidatorReport) fail(err error) {
+	r.Status = ValidatorStatusFailed
+	if errors.Is(err, repository.ErrPreflightRevert) {
+		r.Status = ValidatorStatusReverted
+	}
+	r.Error = err.Error()
+}
+
+// Count returns the number of validators that ended the run with the given status.
+func (r RunReport) Count(status ValidatorStatus) int {
+	count := 0
+	for _, validator := range r.Validators {
+		if validator.Status == status {
+			count++
+		}
+	}
+	return count
+}
+
+func (r RunReport) Log() {
+	for _, validator := range r.Validators {
+		actions := make([]string, 0, len(validator.Actions))
+		for _, action := range validator.Actions {
+			actions = append(actions, fmt.Sprintf("%s %s %s", action.Method, action.Amount, action.TransactionHash))
+		}
+		line := fmt.Sprintf("Validator %s (operator %s): %s", validator.Pubkey, validator.OperatorAddress, validator.Status)
+		if len(actions) > 0 {
+			line += fmt.Sprintf(" [%s]", strings.Join(actions, ", "))
+		}
+		for _, deferred := range validator.Deferred {
+			line += fmt.Sprintf(" deferred %s %s: %s;", deferred.Method, deferred.Amount, deferred.Reason)
+		}
+		if validator.QueueReset != nil {
+			line += fmt.Sprintf(" pending %s: reset costs %d blocks, hold costs %d blocks, chose %s;",
+				validator.QueueReset.PendingAmount, validator.QueueReset.ResetDelayBlocks, validator.QueueReset.HoldDelayBlocks, validator.QueueReset.Choice)
+		}
+		if validator.AtMaxBoost {
+			line += " at max boost"
+		}
+		if validator.Error != "" {
+			line += fmt.Sprintf(" error: %s", validator.Error)
+		}
+		log.Println(line)
+	}
+	log.Printf("Boost run finished in %s: %d queued, %d activated, %d drop queued, %d dropped, %d deferred, %d skipped, %d disabled, %d reverted, %d failed",
+		r.FinishedAt.Sub(r.StartedAt).Round(time.Millisecond),
+		r.Count(ValidatorStatusQueued),
+		r.Count(ValidatorStatusActivated),
+		r.Count(ValidatorStatusDropQueued),
+		r.Count(ValidatorStatusDropped),
+		r.Count(ValidatorStatusDeferred),
+		r.Count(ValidatorStatusSkipped),
+		r.Count(ValidatorStatusDisabled),
+		r.Count(ValidatorStatusReverted),
+		r.Count(ValidatorStatusFailed),
+	)
+
+	var atMaxBoost []string
+	for _, validator := range r.Validators {
+		if validator.AtMaxBoost {
+			atMaxBoost = append(atMaxBoost, validator.Pubkey)
+		}
+	}
+	if len(atMaxBoost) > 0 {
+		log.Printf("Validators at max boost: %s", strings.Join(atMaxBoost, ", "))
+	}
+}
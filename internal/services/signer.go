@@ -55,8 +55,8 @@ func (s *signerService) SignTransaction(ctx context.Context, fromAddress string,
 		From:                 fromAddress,
 		To:                   tx.To().Hex(),
 		Gas:                  fmt.Sprintf("0x%x", tx.Gas()),
-		MaxFeePerGas:         fmt.Sprintf("0x%x", tx.GasPrice()),
-		MaxPriorityFeePerGas: fmt.Sprintf("0x%x", tx.GasPrice()),
+		MaxFeePerGas:         fmt.Sprintf("0x%x", tx.GasFeeCap()),
+		MaxPriorityFeePerGas: fmt.Sprintf("0x%x", tx.GasTipCap()),
 		Value:                fmt.Sprintf("0x%x", tx.Value()),
 		Nonce:                fmt.Sprintf("0x%x", tx.Nonce()),
 		Data:                 hex.EncodeToString(tx.Data()),
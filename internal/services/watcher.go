@@ -0,0 +1,118 @@
+package services
+
+import (
+	"bgt_boost/internal/config"
+	"bgt_boost/internal/repository"
+	"context"
+	"log"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// maxInflowBlockRange bounds how far back one log query reaches after the watcher fell behind. Older inflows are
+// left to the cron sweep.
+const maxInflowBlockRange = 1000
+
+// BlockWatcher follows new blocks and reports the operators that received BGT in them, so that their validators can
+// be boosted without waiting for the next scheduled run.
+type BlockWatcher struct {
+	config        *config.Config
+	dbRepository  *repository.DbRepository
+	ethRepository *repository.EthRepository
+	onInflow      func(ctx context.Context, operatorAddresses []common.Address)
+}
+
+func NewBlockWatcher(config *config.Config, dbRepository *repository.DbRepository, ethRepository *repository.EthRepository, onInflow func(ctx context.Context, operatorAddresses []common.Address)) *BlockWatcher {
+	return &BlockWatcher{
+		config:        config,
+		dbRepository:  dbRepository,
+		ethRepository: ethRepository,
+		onInflow:      onInflow,
+	}
+}
+
+// Run watches blocks until ctx is cancelled. Operators that receive BGT are collected until no new inflow has been
+// seen for the debounce period, or the first of them has waited for the max wait, then handed to onInflow in one
+// call. Only one call runs at a time; inflows seen while it runs are handled once it returns.
+func (w *BlockWatcher) Run(ctx context.Context) {
+	heads := make(chan uint64)
+	go watchHeads(ctx, w.ethRepository, w.config.EventPollSeconds, heads)
+
+	debounce := time.Duration(w.config.EventDebounceSeconds) * time.Second
+	maxWait := time.Duration(w.config.EventMaxWaitSeconds) * time.Second
+	pending := make(map[common.Address]bool)
+	// Buffered so that a call still running when ctx is cancelled can finish without anyone receiving
+	done := make(chan struct{}, 1)
+	running := false
+	var timer <-chan time.Time
+	var deadline time.Time
+	var lastBlock uint64
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case head := <-heads:
+			// An endpoint behind the one that answered before can report an older head
+			if head <= lastBlock {
+				continue
+			}
+			if lastBlock == 0 || head-lastBlock > maxInflowBlockRange {
+				lastBlock = head - 1
+			}
+			recipients, err := w.getInflows(ctx, lastBlock+1, head)
+			if err != nil {
+				log.Printf("Failed to check BGT inflows up to block %d: %v", head, err)
+				continue
+			}
+			lastBlock = head
+			for _, recipient := range recipients {
+				log.Printf("Operator %s received BGT by block %d", recipient.Hex(), head)
+				pending[recipient] = true
+			}
+			if len(recipients) > 0 {
+				if deadline.IsZero() {
+					deadline = time.Now().Add(maxWait)
+				}
+				timer = time.After(min(debounce, time.Until(deadline)))
+			}
+		case <-timer:
+			timer = nil
+			if running || len(pending) == 0 {
+				continue
+			}
+			operatorAddresses := make([]common.Address, 0, len(pending))
+			for address := range pending {
+				operatorAddresses = append(operatorAddresses, address)
+			}
+			pending = make(map[common.Address]bool)
+			deadline = time.Time{}
+			running = true
+			go func() {
+				w.onInflow(ctx, operatorAddresses)
+				done <- struct{}{}
+			}()
+		case <-done:
+			running = false
+			if len(pending) > 0 {
+				timer = time.After(min(debounce, time.Until(deadline)))
+			}
+		}
+	}
+}
+
+// getInflows returns the enabled operators that received BGT between fromBlock and toBlock.
+func (w *BlockWatcher) getInflows(ctx context.Context, fromBlock uint64, toBlock uint64) ([]common.Address, error) {
+	operators, err := (*w.dbRepository).GetOperators(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var addresses []common.Address
+	for _, operator := range operators {
+		if operator.Enabled {
+			addresses = append(addresses, common.HexToAddress(operator.Address))
+		}
+	}
+	return (*w.ethRepository).GetTransferRecipients(ctx, fromBlock, toBlock, addresses)
+}
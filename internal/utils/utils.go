@@ -14,6 +14,19 @@ func ConvertWeiToEther(wei *big.Int) float64 {
 	return result
 }
 
+func ConvertWeiToGwei(wei *big.Int) float64 {
+	gweiValue := new(big.Float).SetInt(wei)
+	gweiValue.Quo(gweiValue, big.NewFloat(1e9)) // Divide by 10^9
+	result, _ := gweiValue.Float64()
+	return result
+}
+
+func ConvertGweiToWei(gwei float64) *big.Int {
+	weiValue := new(big.Float).Mul(big.NewFloat(gwei), big.NewFloat(1e9))
+	result, _ := weiValue.Int(nil)
+	return result
+}
+
 func PrintNextExecution(c *cron.Cron) {
 	entries := c.Entries()
 	if len(entries) > 0 {